@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
@@ -20,7 +21,7 @@ type CertCheck struct {
 	minValidFor   time.Duration
 }
 
-func NewCertCheck(environmentId, checkId, name string, params map[string]string) (*CertCheck, error) {
+func NewCertCheck(environmentId, checkId, name string, timeout time.Duration, params map[string]string) (*CertCheck, error) {
 	c := &CertCheck{
 		environmentId: environmentId,
 		checkId:       checkId,
@@ -38,15 +39,11 @@ func NewCertCheck(environmentId, checkId, name string, params map[string]string)
 		c.port = 443
 	}
 
-	if t, exist := params["timeout"]; exist {
-		d, err := time.ParseDuration(t)
-		if err != nil {
-			return nil, errors.Wrapf(err, "parsing timeout")
-		}
-		c.timeout = d
-	} else {
-		c.timeout = time.Second * 10
+	d, err := checkTimeout(timeout, params)
+	if err != nil {
+		return nil, err
 	}
+	c.timeout = d
 
 	if t, exist := params["minValidFor"]; exist {
 		d, err := time.ParseDuration(t)
@@ -61,10 +58,10 @@ func NewCertCheck(environmentId, checkId, name string, params map[string]string)
 	return c, nil
 }
 
-func (c *CertCheck) Check() []Result {
+func (c *CertCheck) Check(ctx context.Context) []Result {
 	mainResult := NewResult(c.environmentId, c.checkId, c.name)
 
-	mainResult.Status, mainResult.Message, mainResult.Detail = c.execute()
+	mainResult.Status, mainResult.Message, mainResult.CertExpiry = c.execute(ctx)
 
 	mainResult.Duration = int(time.Since(mainResult.Timestamp) / time.Millisecond)
 
@@ -73,13 +70,20 @@ func (c *CertCheck) Check() []Result {
 	return results
 }
 
-func (c *CertCheck) execute() (status, message, detail string) {
-	conn, err := tls.Dial("tcp", c.hostAndPort(), &tls.Config{})
+func (c *CertCheck) execute(ctx context.Context) (status, message string, notAfter time.Time) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	dialer := &tls.Dialer{
+		Config: &tls.Config{},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", c.hostAndPort())
 	if err != nil {
-		return StatusDown, err.Error(), ""
+		return StatusDown, err.Error(), time.Time{}
 	}
+	defer conn.Close()
 
-	state := conn.ConnectionState()
+	state := conn.(*tls.Conn).ConnectionState()
 
 	intermidiates := x509.NewCertPool()
 	for _, c := range state.PeerCertificates {
@@ -94,11 +98,11 @@ func (c *CertCheck) execute() (status, message, detail string) {
 	})
 
 	if err != nil {
-		return StatusDown, err.Error(), ""
+		return StatusDown, err.Error(), cert.NotAfter
 	}
 
 	message = fmt.Sprintf("Valid from %v to %v", cert.NotBefore, cert.NotAfter)
-	return StatusUp, message, ""
+	return StatusUp, message, cert.NotAfter
 }
 
 func (c *CertCheck) hostAndPort() string {
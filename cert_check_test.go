@@ -1,7 +1,11 @@
 package main
 
 import (
+	"context"
+	"net"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -12,13 +16,14 @@ func Test_CertCheck_Success(t *testing.T) {
 		"prod",
 		"test-check",
 		"test check",
+		0,
 		map[string]string{
 			"host":        "valid-isrgrootx1.letsencrypt.org",
 			"minValidFor": "1s",
 		},
 	)
 
-	results := check.Check()
+	results := check.Check(context.Background())
 
 	require.Equal(t, 1, len(results))
 
@@ -31,6 +36,7 @@ func Test_CertCheck_WillExpire(t *testing.T) {
 		"prod",
 		"test-check",
 		"test check",
+		0,
 		map[string]string{
 			"host":        "valid-isrgrootx1.letsencrypt.org",
 			"port":        "443",
@@ -39,7 +45,7 @@ func Test_CertCheck_WillExpire(t *testing.T) {
 	)
 	require.NoError(t, err)
 
-	results := check.Check()
+	results := check.Check(context.Background())
 
 	require.Equal(t, 1, len(results))
 
@@ -52,6 +58,7 @@ func Test_CertCheck_Expired(t *testing.T) {
 		"prod",
 		"test-check",
 		"test check",
+		0,
 		map[string]string{
 			"host":        "expired-isrgrootx1.letsencrypt.org",
 			"port":        "443",
@@ -60,10 +67,46 @@ func Test_CertCheck_Expired(t *testing.T) {
 	)
 	require.NoError(t, err)
 
-	results := check.Check()
+	results := check.Check(context.Background())
 
 	require.Equal(t, 1, len(results))
 
 	result := results[0]
 	assert.Equal(t, StatusDown, result.Status)
 }
+
+func Test_CertCheck_Timeout(t *testing.T) {
+	// a server, which accepts connections, but never answers the tls handshake
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	check, err := NewCertCheck(
+		"prod",
+		"test-check",
+		"test check",
+		100*time.Millisecond,
+		map[string]string{
+			"host":    "127.0.0.1",
+			"port":    strconv.Itoa(listener.Addr().(*net.TCPAddr).Port),
+			"timeout": "1h",
+		},
+	)
+	require.NoError(t, err)
+
+	start := time.Now()
+	results := check.Check(context.Background())
+
+	require.Equal(t, 1, len(results))
+	assert.Equal(t, StatusDown, results[0].Status)
+	assert.True(t, time.Since(start) < time.Second)
+}
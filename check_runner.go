@@ -1,86 +1,236 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"os"
+	"reflect"
+	"sync"
 	"time"
+
+	"github.com/pkg/errors"
 )
 
 type checkJob struct {
 	checker Checker
+	timeout time.Duration
 	done    chan bool
 }
 
-func startChecking(cfg *Config, resultCallback chan []Result) {
-	checkQueue := make(chan checkJob, 50)
-	for _, e := range cfg.Environments {
+// CheckRunner schedules the checks and executes them by a pool of workers.
+type CheckRunner struct {
+	cfg            *Config
+	checkQueue     chan checkJob
+	resultCallback chan []Result
+
+	// protects running and stopped
+	mutex   sync.Mutex
+	running map[string]*runningCheck
+	stopped bool
+
+	// shedulerCtx is cancelled to stop the schedulers
+	shedulerCtx   context.Context
+	stopShedulers context.CancelFunc
+	shedulers     sync.WaitGroup
+	// checkCtx is cancelled to abort the running checks
+	checkCtx    context.Context
+	abortChecks context.CancelFunc
+	workers     sync.WaitGroup
+}
+
+// runningCheck is a scheduled check, which can be stopped on its own.
+type runningCheck struct {
+	check Check
+	stop  context.CancelFunc
+}
+
+func NewCheckRunner(cfg *Config, resultCallback chan []Result) *CheckRunner {
+	r := &CheckRunner{
+		cfg:            cfg,
+		checkQueue:     make(chan checkJob, 50),
+		resultCallback: resultCallback,
+		running:        map[string]*runningCheck{},
+	}
+	r.shedulerCtx, r.stopShedulers = context.WithCancel(context.Background())
+	r.checkCtx, r.abortChecks = context.WithCancel(context.Background())
+	return r
+}
+
+// Start starts the schedulers and workers for all checks.
+func (r *CheckRunner) Start() error {
+	err := r.Reload(r.cfg.Envs())
+	if err != nil {
+		return err
+	}
+
+	go monitorQueue(r.shedulerCtx, r.cfg, r.checkQueue)
+
+	for i := 0; i < r.cfg.Worker; i++ {
+		r.workers.Add(1)
+		go func() {
+			defer r.workers.Done()
+			worker(r.checkCtx, r.checkQueue, r.resultCallback)
+		}()
+	}
+	return nil
+}
+
+// Reload applies the checks of the environments: New checks are started, removed checks
+// are stopped and changed checks are restarted. If one of the checks can not be created,
+// the running checks stay untouched.
+func (r *CheckRunner) Reload(envs []Env) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.stopped {
+		return errors.New("check runner is stopped")
+	}
+
+	checks := map[string]Check{}
+	checkers := map[string]Checker{}
+	for _, e := range envs {
 		for _, c := range e.Checks {
 			if len(c.Envs) > 0 && !contains(c.Envs, e.Id) {
 				continue
 			}
-			var checker Checker
-			var err error
-			switch c.Type {
-			case "http":
-				checker, err = NewHttpCheck(e.Id, c.Id, c.Name, c.Params)
-				if err != nil {
-					log.Fatalf("error creating check %v/%v: %v\n", e.Id, c.Id, err)
-				}
-			case "sftp":
-				checker, err = NewSftpCheck(e.Id, c.Id, c.Name, c.Params)
-				if err != nil {
-					log.Fatalf("error creating check %v/%v: %v\n", e.Id, c.Id, err)
-				}
-			case "cert":
-				checker, err = NewCertCheck(e.Id, c.Id, c.Name, c.Params)
-				if err != nil {
-					log.Fatalf("error creating check %v/%v: %v\n", e.Id, c.Id, err)
-				}
-			default:
-				log.Fatalf("error creating check for %v/%v: no such type %v\n", e.Id, c.Id, c.Type)
+			key := e.Id + "/" + c.Id
+			checks[key] = c
+			if running, exist := r.running[key]; exist && reflect.DeepEqual(running.check, c) {
+				continue
 			}
-			d := cfg.Duration
-			if c.Every != 0 {
-				d = c.Every
+			checker, err := newChecker(e.Id, c)
+			if err != nil {
+				return errors.Wrapf(err, "error creating check %v", key)
 			}
-			go shedule(checker, d, checkQueue)
+			checkers[key] = checker
 		}
 	}
 
-	go monitorQueue(cfg, checkQueue)
+	for key, running := range r.running {
+		if _, exist := checks[key]; exist && checkers[key] == nil {
+			continue
+		}
+		running.stop()
+		delete(r.running, key)
+		if _, exist := checks[key]; !exist {
+			log.Printf("stopped check %v\n", key)
+		}
+	}
+
+	for key, checker := range checkers {
+		c := checks[key]
+		d := r.cfg.Duration
+		if c.Every != 0 {
+			d = c.Every
+		}
+		timeout, _ := checkTimeout(c.Timeout, c.Params)
+		if c.Retries > 0 {
+			checker = NewRetryChecker(checker, c.Retries, c.RetryDelay)
+			// the deadline has to cover all attempts
+			timeout = time.Duration(c.Retries+1)*timeout + time.Duration(c.Retries)*c.RetryDelay
+		}
 
-	for i := 0; i < cfg.Worker; i++ {
-		go worker(checkQueue, resultCallback)
+		ctx, stop := context.WithCancel(r.shedulerCtx)
+		r.running[key] = &runningCheck{check: c, stop: stop}
+		r.shedulers.Add(1)
+		go func(checker Checker) {
+			defer r.shedulers.Done()
+			shedule(ctx, checker, d, timeout, r.checkQueue)
+		}(checker)
 	}
+	if len(checkers) > 0 {
+		log.Printf("started %v checks\n", len(checkers))
+	}
+	return nil
 }
 
-func monitorQueue(cfg *Config, checkQueue chan checkJob) {
-	ticker := time.Tick(time.Second * 20)
+// Stop stops the schedulers and waits until the workers have processed all queued checks.
+// If the context is done before, the running checks are aborted.
+// At the end, the result callback is closed.
+func (r *CheckRunner) Stop(ctx context.Context) {
+	r.mutex.Lock()
+	r.stopped = true
+	r.mutex.Unlock()
+
+	r.stopShedulers()
+	r.shedulers.Wait()
+	close(r.checkQueue)
+
+	workersDone := make(chan bool)
+	go func() {
+		r.workers.Wait()
+		close(workersDone)
+	}()
+
+	select {
+	case <-workersDone:
+	case <-ctx.Done():
+		log.Printf("aborting running checks: %v\n", ctx.Err())
+		r.abortChecks()
+		<-workersDone
+	}
+	r.abortChecks()
+	close(r.resultCallback)
+}
+
+// QueueLength returns the number of checks, waiting for a free worker.
+func (r *CheckRunner) QueueLength() int {
+	return len(r.checkQueue)
+}
+
+func monitorQueue(ctx context.Context, cfg *Config, checkQueue chan checkJob) {
+	ticker := time.NewTicker(time.Second * 20)
+	defer ticker.Stop()
 	for {
 		if len(checkQueue) > cfg.Worker {
 			log.Printf("WARNING: queue size: %v", len(checkQueue))
 		}
-		<-ticker
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
 	}
 }
 
-func worker(checkQueue chan checkJob, resultCallback chan []Result) {
-	for {
-		job := <-checkQueue
-		results := job.checker.Check()
+// worker executes the checks from the queue, until the queue is closed.
+// The results of checks, which were aborted on shutdown, are dropped,
+// because their failure tells nothing about the checked system.
+func worker(ctx context.Context, checkQueue chan checkJob, resultCallback chan []Result) {
+	for job := range checkQueue {
+		checkCtx, cancel := context.WithTimeout(ctx, job.timeout)
+		results := job.checker.Check(checkCtx)
+		cancel()
 		job.done <- true
+		if ctx.Err() != nil {
+			continue
+		}
 		resultCallback <- results
 	}
 }
 
-func shedule(checker Checker, d time.Duration, checkQueue chan checkJob) {
-	ticker := time.Tick(d)
+func shedule(ctx context.Context, checker Checker, d, timeout time.Duration, checkQueue chan checkJob) {
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
 	for {
-		done := make(chan bool)
-		checkQueue <- checkJob{checker, done}
-		<-done
-		<-ticker
+		// buffered, so that the worker does not block, if we are already gone
+		done := make(chan bool, 1)
+		select {
+		case checkQueue <- checkJob{checker, timeout, done}:
+		case <-ctx.Done():
+			return
+		}
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
 	}
 }
 
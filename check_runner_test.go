@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Worker_AbortsCheckAfterTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	checkQueue := make(chan checkJob, 1)
+	resultCallback := make(chan []Result, 1)
+	go worker(ctx, checkQueue, resultCallback)
+
+	done := make(chan bool, 1)
+	checkQueue <- checkJob{&blockingChecker{}, 50 * time.Millisecond, done}
+
+	select {
+	case results := <-resultCallback:
+		require.Equal(t, 1, len(results))
+		assert.Equal(t, StatusDown, results[0].Status)
+		assert.Equal(t, context.DeadlineExceeded.Error(), results[0].Message)
+	case <-time.After(time.Second):
+		t.Fatal("check was not aborted")
+	}
+	assert.True(t, <-done)
+}
+
+func Test_Shedule_StopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	checker := &countingChecker{}
+	checkQueue := make(chan checkJob, 10)
+	resultCallback := make(chan []Result, 10)
+	go worker(ctx, checkQueue, resultCallback)
+
+	stopped := make(chan bool)
+	go func() {
+		shedule(ctx, checker, 10*time.Millisecond, time.Second, checkQueue)
+		close(stopped)
+	}()
+
+	time.Sleep(55 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler did not stop")
+	}
+	count := atomic.LoadInt32(&checker.count)
+	assert.True(t, count >= 3, "count was %v", count)
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, count, atomic.LoadInt32(&checker.count))
+}
+
+// blockingChecker blocks until the context is done.
+type blockingChecker struct{}
+
+func (c *blockingChecker) Check(ctx context.Context) []Result {
+	<-ctx.Done()
+	return []Result{NewResultFromError("testEnv", "blocking", "blocking", ctx.Err())}
+}
+
+type countingChecker struct {
+	count int32
+}
+
+func (c *countingChecker) Check(ctx context.Context) []Result {
+	atomic.AddInt32(&c.count, 1)
+	return []Result{NewResult("testEnv", "counting", "counting")}
+}
+
+func Test_CheckRunner_StopDrainsResults(t *testing.T) {
+	cfg := runnerTestConfig(Check{
+		Id:     "check1",
+		Type:   "exec",
+		Every:  10 * time.Millisecond,
+		Params: map[string]string{"command": "/bin/sh", "args": "-c 'sleep 0.05'"},
+	})
+	resultCallback := make(chan []Result, 50)
+	runner := NewCheckRunner(cfg, resultCallback)
+	runner.Start()
+
+	time.Sleep(20 * time.Millisecond)
+	runner.Stop(context.Background())
+
+	count := 0
+	for results := range resultCallback {
+		for _, r := range results {
+			assert.Equal(t, StatusUp, r.Status, r.Message)
+			count++
+		}
+	}
+	assert.True(t, count >= 1)
+}
+
+func Test_CheckRunner_StopAbortsChecks(t *testing.T) {
+	cfg := runnerTestConfig(Check{
+		Id:      "check1",
+		Type:    "exec",
+		Timeout: time.Minute,
+		Params:  map[string]string{"command": "/bin/sh", "args": "-c 'sleep 10'"},
+	})
+	resultCallback := make(chan []Result, 50)
+	runner := NewCheckRunner(cfg, resultCallback)
+	runner.Start()
+
+	time.Sleep(20 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	runner.Stop(ctx)
+	assert.True(t, time.Since(start) < 5*time.Second)
+
+	// the aborted check emits no result
+	_, open := <-resultCallback
+	assert.False(t, open)
+}
+
+func runnerTestConfig(checks ...Check) *Config {
+	return &Config{
+		Worker:   2,
+		Duration: time.Minute,
+		Environments: []Env{
+			{
+				Id:     "testEnv",
+				Checks: checks,
+			},
+		},
+	}
+}
+
+func Test_CheckRunner_Reload(t *testing.T) {
+	check1 := Check{Id: "check1", Type: "exec", Every: time.Hour, Params: map[string]string{"command": "/bin/true"}}
+	check2 := Check{Id: "check2", Type: "exec", Every: time.Hour, Params: map[string]string{"command": "/bin/true"}}
+	cfg := runnerTestConfig(check1, check2)
+	resultCallback := make(chan []Result, 50)
+	runner := NewCheckRunner(cfg, resultCallback)
+	require.NoError(t, runner.Start())
+	defer runner.Stop(context.Background())
+
+	// each started check is executed once immediately, so we can count the (re)starts
+	executions := map[string]int{}
+	assertExecutions := func(expected map[string]int) {
+		time.Sleep(100 * time.Millisecond)
+		for len(resultCallback) > 0 {
+			for _, r := range <-resultCallback {
+				executions[r.Check]++
+			}
+		}
+		assert.Equal(t, expected, executions)
+	}
+	assertRunning := func(expected ...string) {
+		runner.mutex.Lock()
+		defer runner.mutex.Unlock()
+		running := []string{}
+		for key := range runner.running {
+			running = append(running, key)
+		}
+		assert.ElementsMatch(t, expected, running)
+	}
+
+	assertRunning("testEnv/check1", "testEnv/check2")
+	assertExecutions(map[string]int{"check1": 1, "check2": 1})
+
+	// invalid checks are rejected
+	invalid := Check{Id: "check3", Type: "unknown"}
+	assert.Error(t, runner.Reload(runnerTestConfig(check1, invalid).Environments))
+	assertRunning("testEnv/check1", "testEnv/check2")
+	assertExecutions(map[string]int{"check1": 1, "check2": 1})
+
+	// change check1, remove check2 and add check3
+	changed := check1
+	changed.Every = 2 * time.Hour
+	check3 := Check{Id: "check3", Type: "exec", Every: time.Hour, Params: map[string]string{"command": "/bin/true"}}
+	require.NoError(t, runner.Reload(runnerTestConfig(changed, check3).Environments))
+	assertRunning("testEnv/check1", "testEnv/check3")
+	assertExecutions(map[string]int{"check1": 2, "check2": 1, "check3": 1})
+
+	// unchanged checks are kept running
+	require.NoError(t, runner.Reload(runnerTestConfig(changed, check3).Environments))
+	assertRunning("testEnv/check1", "testEnv/check3")
+	assertExecutions(map[string]int{"check1": 2, "check2": 1, "check3": 1})
+}
@@ -1,5 +1,58 @@
 package main
 
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var defaultCheckTimeout = time.Second * 10
+
+// Checker executes a check. The check has to be aborted, when the context is done.
 type Checker interface {
-	Check() []Result
+	Check(ctx context.Context) []Result
+}
+
+// newChecker creates the checker for the type of the check.
+func newChecker(envId string, c Check) (Checker, error) {
+	var checker Checker
+	var err error
+	switch c.Type {
+	case "http":
+		checker, err = NewHttpCheck(envId, c.Id, c.Name, c.Timeout, c.Params)
+	case "sftp":
+		checker, err = NewSftpCheck(envId, c.Id, c.Name, c.Timeout, c.Params)
+	case "cert":
+		checker, err = NewCertCheck(envId, c.Id, c.Name, c.Timeout, c.Params)
+	case "tcp":
+		checker, err = NewTcpCheck(envId, c.Id, c.Name, c.Timeout, c.Params)
+	case "dns":
+		checker, err = NewDnsCheck(envId, c.Id, c.Name, c.Timeout, c.Params)
+	case "exec":
+		checker, err = NewExecCheck(envId, c.Id, c.Name, c.Timeout, c.Params)
+	default:
+		return nil, fmt.Errorf("no such type %v", c.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return checker, nil
+}
+
+// checkTimeout returns the timeout of a check. The timeout of the check configuration
+// takes precedence over the timeout param. Without both, the default timeout is used.
+func checkTimeout(timeout time.Duration, params map[string]string) (time.Duration, error) {
+	if timeout > 0 {
+		return timeout, nil
+	}
+	if t, exist := params["timeout"]; exist {
+		d, err := time.ParseDuration(t)
+		if err != nil {
+			return 0, errors.Wrapf(err, "parsing timeout")
+		}
+		return d, nil
+	}
+	return defaultCheckTimeout, nil
 }
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CheckTimeout(t *testing.T) {
+	d, err := checkTimeout(2*time.Second, map[string]string{"timeout": "5s"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2*time.Second, d)
+
+	d, err = checkTimeout(0, map[string]string{"timeout": "5s"})
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Second, d)
+
+	d, err = checkTimeout(0, map[string]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, defaultCheckTimeout, d)
+
+	_, err = checkTimeout(0, map[string]string{"timeout": "5 seconds"})
+	assert.Error(t, err)
+}
@@ -2,53 +2,254 @@ package main
 
 import (
 	"flag"
-	"gopkg.in/yaml.v2"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
 )
 
+var defaultFailThreshold = 2
+
+var defaultSeverity = "warning"
+
 type Env struct {
 	Id            string            `yaml:"id"`
 	Name          string            `yaml:"name"`
 	Default       bool              `yaml:"default"`
 	Vars          map[string]string `yaml:"vars"`
 	Notifications []Notification    `yaml:"notifications"`
+	FailThreshold int               `yaml:"failThreshold"`
+	FailDuration  time.Duration     `yaml:"failDuration"`
+	Maintenance   []Maintenance     `yaml:"maintenance"`
 	Checks        []Check
 }
 
 type Notification struct {
-	Type             string `yaml:"type"`
-	Target           string `yaml:"target"`
-	AlertAtDaytime   bool   `yaml:"alertAtDaytime"`
-	AlertAtNighttime bool   `yaml:"alertAtNighttime"`
+	Type             string            `yaml:"type"`
+	Target           string            `yaml:"target"`
+	AlertAtDaytime   bool              `yaml:"alertAtDaytime"`
+	AlertAtNighttime bool              `yaml:"alertAtNighttime"`
+	Statuses         []string          `yaml:"statuses"`
+	Teams            []string          `yaml:"teams"`
+	Severities       []string          `yaml:"severities"`
+	Labels           map[string]string `yaml:"labels"`
+	AlertSeverities  []string          `yaml:"alertSeverities"`
+	DayStart         string            `yaml:"dayStart"`
+	DayEnd           string            `yaml:"dayEnd"`
+	WorkDays         []string          `yaml:"workDays"`
+	Timezone         string            `yaml:"timezone"`
+	HolidaysFile     string            `yaml:"holidaysFile"`
+	RemindEvery      time.Duration     `yaml:"remindEvery"`
+	EscalateAfter    time.Duration     `yaml:"escalateAfter"`
+	Params           map[string]string `yaml:"params"`
+
+	// the dates of the HolidaysFile, read on load
+	holidays map[string]bool
+}
+
+// reminderDue returns true, if the notification has to remind of the downtime at now.
+// The reminders are sent every RemindEvery after the down notification, while the downtime is DOWN.
+func (n Notification) reminderDue(d *Downtime, now time.Time) bool {
+	if n.RemindEvery <= 0 || !d.DownNotifySent || d.Status != StatusDown || !n.receives(StatusDown) || !n.escalatedTo(d) {
+		return false
+	}
+	last := d.LastReminderTime
+	if last.Before(d.DownNotifyTime) {
+		last = d.DownNotifyTime
+	}
+	return now.Sub(d.DownNotifyTime)/n.RemindEvery > last.Sub(d.DownNotifyTime)/n.RemindEvery
+}
+
+// receives returns true, if the target gets the notifications for the status.
+// Without statuses, a target only gets the notifications for DOWN.
+func (n Notification) receives(status string) bool {
+	if len(n.Statuses) == 0 {
+		return status == StatusDown
+	}
+	for _, s := range n.Statuses {
+		if strings.EqualFold(s, status) {
+			return true
+		}
+	}
+	return false
+}
+
+// routes returns true, if the notifications of the check are sent to the target.
+// A target without teams, severities or labels gets the notifications of all checks.
+func (n Notification) routes(c Check) bool {
+	if len(n.Teams) > 0 && !contains(n.Teams, c.Team) {
+		return false
+	}
+	if len(n.Severities) > 0 && !contains(n.Severities, c.severity()) {
+		return false
+	}
+	for k, v := range n.Labels {
+		if c.Labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// alertsFor returns true, if one of the checks has a severity, which triggers an alert on the target.
+// Without alertSeverities, all checks trigger alerts.
+func (n Notification) alertsFor(checks []Check) bool {
+	if len(n.AlertSeverities) == 0 {
+		return true
+	}
+	for _, c := range checks {
+		if contains(n.AlertSeverities, c.severity()) {
+			return true
+		}
+	}
+	return false
+}
+
+// notifiedOf returns true, if the target got the down or degraded notification of the downtime.
+func (n Notification) notifiedOf(d *Downtime) bool {
+	return (d.DownNotifySent && n.receives(StatusDown) && n.escalatedTo(d)) ||
+		(d.DegradedNotifySent && n.receives(StatusDegraded) && n.EscalateAfter <= 0)
+}
+
+// escalatedTo returns true, if the downtime is escalated to the target.
+// Targets without an escalation delay get all notifications.
+func (n Notification) escalatedTo(d *Downtime) bool {
+	return n.EscalateAfter <= d.EscalatedAfter
+}
+
+// escalationDue returns true, if the downtime has to be escalated to the notification at now.
+// A downtime is only escalated, while it is DOWN.
+func (n Notification) escalationDue(d *Downtime, now time.Time) bool {
+	return n.EscalateAfter > 0 && d.DownNotifySent && d.Status == StatusDown && !d.Acknowledged && n.receives(StatusDown) &&
+		!n.escalatedTo(d) && now.Sub(d.Start) >= n.EscalateAfter
+}
+
+// Maintenance is a planned window, in which failures of the checks are expected.
+// It is either a one-off window from Start to End (or Start + Duration), or a
+// recurring one, which starts on the Cron schedule and lasts for Duration.
+type Maintenance struct {
+	Id       string        `yaml:"id"`
+	Checks   []string      `yaml:"checks"`
+	Start    time.Time     `yaml:"start"`
+	End      time.Time     `yaml:"end"`
+	Cron     string        `yaml:"cron"`
+	Duration time.Duration `yaml:"duration"`
+	Timezone string        `yaml:"timezone"`
+	Comment  string        `yaml:"comment"`
 }
 
 type Check struct {
-	Id      string            `yaml:"id"`
-	Name    string            `yaml:"name"`
-	Type    string            `yaml:"type"`
-	Every   time.Duration     `yaml:"every"`
-	Timeout time.Duration     `yaml:"timeout"`
-	Envs    []string          `yaml:"envs"`
-	Params  map[string]string `yaml:"params"`
+	Id            string            `yaml:"id"`
+	Name          string            `yaml:"name"`
+	Type          string            `yaml:"type"`
+	Every         time.Duration     `yaml:"every"`
+	Timeout       time.Duration     `yaml:"timeout"`
+	FailThreshold int               `yaml:"failThreshold"`
+	FailDuration  time.Duration     `yaml:"failDuration"`
+	Retries       int               `yaml:"retries"`
+	RetryDelay    time.Duration     `yaml:"retryDelay"`
+	Severity      string            `yaml:"severity"`
+	Team          string            `yaml:"team"`
+	Labels        map[string]string `yaml:"labels"`
+	Envs          []string          `yaml:"envs"`
+	Params        map[string]string `yaml:"params"`
+}
+
+func (c Check) severity() string {
+	if c.Severity == "" {
+		return defaultSeverity
+	}
+	return c.Severity
+}
+
+func (e Env) hasCheck(checkId string) bool {
+	_, exist := e.checkById(checkId)
+	return exist
+}
+
+func (e Env) checkById(checkId string) (Check, bool) {
+	for _, c := range e.Checks {
+		if c.Id == checkId {
+			return c, true
+		}
+	}
+	return Check{}, false
+}
+
+// failThreshold returns the number of failures or the failure duration,
+// after which a downtime of the check triggers notifications.
+// The settings of the check take precedence over the ones of the environment.
+func (e Env) failThreshold(checkId string) (count int, duration time.Duration) {
+	for _, c := range e.Checks {
+		if c.Id == checkId && (c.FailThreshold > 0 || c.FailDuration > 0) {
+			return c.FailThreshold, c.FailDuration
+		}
+	}
+	if e.FailThreshold > 0 || e.FailDuration > 0 {
+		return e.FailThreshold, e.FailDuration
+	}
+	return defaultFailThreshold, 0
+}
+
+// failThresholdReached returns true, if the downtime has reached the fail threshold of its check.
+func (e Env) failThresholdReached(d *Downtime, now time.Time) bool {
+	count, duration := e.failThreshold(d.Check)
+	return (count > 0 && d.FailCount >= count) ||
+		(duration > 0 && now.Sub(d.Start) >= duration)
 }
 
 type Config struct {
-	DBPath       string
-	Listen       string
-	Static       string
-	Worker       int
-	Duration     time.Duration
-	SelfUrl      string
-	Environments []Env
-	Pprof        bool
-	PprofListen  string
+	DBPath           string
+	ChecksPath       string
+	EnvironmentsPath string
+	ConfigPoll       time.Duration
+	Listen           string
+	Static           string
+	Worker           int
+	Duration         time.Duration
+	SelfUrl          string
+	Environments     []Env
+	Pprof            bool
+	PprofListen      string
+	ShutdownTimeout  time.Duration
+
+	// retention of the stored rows, 0 keeps them forever
+	RetainResults       time.Duration
+	RetainDowntimes     time.Duration
+	RetainNotifications time.Duration
+	RetainRollups       time.Duration
+	PruneInterval       time.Duration
+
+	// protects the Environments on a reload
+	mutex sync.RWMutex
+}
+
+// retentionEnabled returns true, if any of the stored rows have a retention.
+func (cfg *Config) retentionEnabled() bool {
+	return cfg.RetainResults > 0 || cfg.RetainDowntimes > 0 || cfg.RetainNotifications > 0 || cfg.RetainRollups > 0
+}
+
+// Envs returns the current environments.
+func (cfg *Config) Envs() []Env {
+	cfg.mutex.RLock()
+	defer cfg.mutex.RUnlock()
+	return cfg.Environments
+}
+
+// SetEnvs replaces the environments after a reload of the configuration.
+func (cfg *Config) SetEnvs(envs []Env) {
+	cfg.mutex.Lock()
+	defer cfg.mutex.Unlock()
+	cfg.Environments = envs
 }
 
 func (cfg *Config) EnvById(envId string) (Env, bool) {
-	for _, e := range cfg.Environments {
+	for _, e := range cfg.Envs() {
 		if e.Id == envId {
 			return e, true
 		}
@@ -66,32 +267,104 @@ func getConfig() (*Config, error) {
 	flag.StringVar(&cfg.SelfUrl, "self-url", "", "Url to reference the this application")
 	flag.BoolVar(&cfg.Pprof, "pprof", false, "Enable the golang pprof interface")
 	flag.StringVar(&cfg.PprofListen, "pprof-listen", ":6060", "Server and port for the profile interface")
+	flag.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", 30*time.Second, "Max time to wait for running checks on shutdown")
+
+	flag.StringVar(&cfg.EnvironmentsPath, "environments", "environments.yml", "The YAML config for the environments")
+	flag.StringVar(&cfg.ChecksPath, "checks", "checks.yml", "The YAML config fot the checks")
+	flag.DurationVar(&cfg.ConfigPoll, "config-poll", 0, "Interval to watch the YAML configs for changes (0 to disable)")
 
-	var checksPath, environmentsPath string
-	flag.StringVar(&environmentsPath, "environments", "environments.yml", "The YAML config for the environments")
-	flag.StringVar(&checksPath, "checks", "checks.yml", "The YAML config fot the checks")
+	flag.DurationVar(&cfg.RetainResults, "retain-results", 0, "Max age of the stored check results (0 to keep them forever)")
+	flag.DurationVar(&cfg.RetainDowntimes, "retain-downtimes", 0, "Max age of the recovered downtimes (0 to keep them forever)")
+	flag.DurationVar(&cfg.RetainNotifications, "retain-notifications", 0, "Max age of the notification history (0 to keep it forever)")
+	flag.DurationVar(&cfg.RetainRollups, "retain-rollups", 0, "Max age of the hour and day rollups, the minute rollups are kept as the results (0 to keep them forever)")
+	flag.DurationVar(&cfg.PruneInterval, "prune-interval", time.Hour, "Interval to delete the rows exceeding their retention, if a retention is set (0 to disable)")
 	flag.Parse()
 
 	var err error
-	cfg.Environments, err = readEnvironments(environmentsPath)
+	cfg.Environments, err = loadEnvironments(cfg.EnvironmentsPath, cfg.ChecksPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// loadEnvironments reads the environments with their checks and validates them.
+func loadEnvironments(environmentsPath, checksPath string) ([]Env, error) {
+	envs, err := readEnvironments(environmentsPath)
 	if err != nil {
 		return nil, err
 	}
 
-	for i, e := range cfg.Environments {
+	for i, e := range envs {
 		allChecks, err := readChecksForEnvironment(checksPath, e)
 		if err != nil {
 			return nil, err
 		}
-		cfg.Environments[i].Checks = []Check{}
+		envs[i].Checks = []Check{}
 		for _, c := range allChecks {
 			if len(c.Envs) == 0 || contains(c.Envs, e.Id) {
-				cfg.Environments[i].Checks = append(cfg.Environments[i].Checks, c)
+				envs[i].Checks = append(envs[i].Checks, c)
+			}
+		}
+
+		for j, n := range e.Notifications {
+			if n.HolidaysFile == "" {
+				continue
+			}
+			envs[i].Notifications[j].holidays, err = readHolidays(n.HolidaysFile)
+			if err != nil {
+				return nil, errors.Wrapf(err, "reading holidays of %v", e.Id)
 			}
 		}
 	}
 
-	return cfg, nil
+	return envs, validateEnvironments(envs)
+}
+
+// validateEnvironments ensures unique ids, that all checks can be created
+// and that the maintenance windows are valid.
+func validateEnvironments(envs []Env) error {
+	envIds := map[string]bool{}
+	for _, e := range envs {
+		if envIds[e.Id] {
+			return fmt.Errorf("duplicate environment %v", e.Id)
+		}
+		envIds[e.Id] = true
+
+		checkIds := map[string]bool{}
+		for _, c := range e.Checks {
+			if checkIds[c.Id] {
+				return fmt.Errorf("duplicate check %v/%v", e.Id, c.Id)
+			}
+			checkIds[c.Id] = true
+
+			_, err := newChecker(e.Id, c)
+			if err != nil {
+				return errors.Wrapf(err, "error creating check %v/%v", e.Id, c.Id)
+			}
+		}
+
+		for _, n := range e.Notifications {
+			_, err := n.daytimeSchedule()
+			if err != nil {
+				return errors.Wrapf(err, "invalid %v notification of %v", n.Type, e.Id)
+			}
+			for _, s := range n.Statuses {
+				if !strings.EqualFold(s, StatusDown) && !strings.EqualFold(s, StatusDegraded) {
+					return fmt.Errorf("invalid status %v in %v notification of %v", s, n.Type, e.Id)
+				}
+			}
+		}
+
+		for _, m := range e.Maintenance {
+			err := m.validate(checkIds)
+			if err != nil {
+				return errors.Wrapf(err, "invalid maintenance %v/%v", e.Id, m.Id)
+			}
+		}
+	}
+	return nil
 }
 
 func readEnvironments(environmentsPath string) ([]Env, error) {
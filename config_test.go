@@ -0,0 +1,158 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_LoadEnvironments(t *testing.T) {
+	envs, err := loadEnvironments(
+		writeTempFile(t, `
+- id: prod
+  name: Production
+- id: testing
+  name: Testing
+  vars:
+    domain: example.org
+`),
+		writeTempFile(t, `
+- id: check1
+  type: http
+  params:
+    url: https://$domain/
+- id: check2
+  type: tcp
+  envs:
+    - testing
+  params:
+    host: $domain
+    port: 80
+`))
+	require.NoError(t, err)
+
+	require.Equal(t, 2, len(envs))
+	assert.Equal(t, 1, len(envs[0].Checks))
+	require.Equal(t, 2, len(envs[1].Checks))
+	assert.Equal(t, "https://example.org/", envs[1].Checks[0].Params["url"])
+	assert.Equal(t, "example.org", envs[1].Checks[1].Params["host"])
+}
+
+func Test_LoadEnvironments_Invalid(t *testing.T) {
+	envs := writeTempFile(t, `
+- id: prod
+`)
+	for _, test := range []struct {
+		name   string
+		checks string
+	}{
+		{"yaml error", `- id: [`},
+		{"unknown type", "- id: check1\n  type: foo\n"},
+		{"invalid params", "- id: check1\n  type: tcp\n  params:\n    host: localhost\n    port: http\n"},
+		{"duplicate check", "- id: check1\n  type: exec\n  params:\n    command: /bin/true\n- id: check1\n  type: exec\n  params:\n    command: /bin/true\n"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := loadEnvironments(envs, writeTempFile(t, test.checks))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_LoadEnvironments_Maintenance(t *testing.T) {
+	checks := writeTempFile(t, "- id: check1\n  type: exec\n  params:\n    command: /bin/true\n")
+	envs, err := loadEnvironments(
+		writeTempFile(t, `
+- id: prod
+  maintenance:
+    - id: deploy
+      cron: "0 22 * * 3"
+      duration: 2h
+      timezone: Europe/Berlin
+    - id: migration
+      checks: [check1]
+      start: 2026-03-10T22:00:00Z
+      end: 2026-03-11T02:00:00Z
+`), checks)
+	require.NoError(t, err)
+
+	m := envs[0].Maintenance
+	require.Equal(t, 2, len(m))
+	assert.Equal(t, 2*time.Hour, m[0].Duration)
+	assert.Equal(t, []string{"check1"}, m[1].Checks)
+	assert.True(t, time.Date(2026, 3, 11, 2, 0, 0, 0, time.UTC).Equal(m[1].End))
+
+	_, err = loadEnvironments(writeTempFile(t, `
+- id: prod
+  maintenance:
+    - id: deploy
+      checks: [check9]
+      cron: "0 22 * * 3"
+      duration: 2h
+`), checks)
+	assert.Error(t, err)
+}
+
+func Test_LoadEnvironments_Holidays(t *testing.T) {
+	checks := writeTempFile(t, "- id: check1\n  type: exec\n  params:\n    command: /bin/true\n")
+	holidays := writeTempFile(t, "2026-12-25 Christmas\n")
+	envs, err := loadEnvironments(writeTempFile(t, `
+- id: prod
+  notifications:
+    - type: slack
+      workDays: [mon, tue, wed, thu, fri]
+      holidaysFile: `+holidays+`
+`), checks)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"2026-12-25": true}, envs[0].Notifications[0].holidays)
+
+	for _, notification := range []string{
+		"holidaysFile: /does/not/exist",
+		"dayStart: 7",
+		"timezone: Mars/Olympus",
+		"statuses: [UP]",
+	} {
+		_, err = loadEnvironments(writeTempFile(t, "- id: prod\n  notifications:\n    - type: slack\n      "+notification+"\n"), checks)
+		assert.Error(t, err, notification)
+	}
+}
+
+func Test_Notification_Routes(t *testing.T) {
+	payments := Check{Id: "checkout", Severity: "critical", Team: "payments", Labels: map[string]string{"tier": "frontend"}}
+	search := Check{Id: "search", Team: "search"}
+
+	for _, test := range []struct {
+		name         string
+		notification Notification
+		expected     []bool
+	}{
+		{"all", Notification{}, []bool{true, true}},
+		{"team", Notification{Teams: []string{"payments"}}, []bool{true, false}},
+		{"severity", Notification{Severities: []string{"critical"}}, []bool{true, false}},
+		{"default severity", Notification{Severities: []string{"warning"}}, []bool{false, true}},
+		{"labels", Notification{Labels: map[string]string{"tier": "frontend"}}, []bool{true, false}},
+		{"team and label", Notification{Teams: []string{"payments"}, Labels: map[string]string{"tier": "backend"}}, []bool{false, false}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, []bool{test.notification.routes(payments), test.notification.routes(search)})
+		})
+	}
+
+	alertCritical := Notification{AlertSeverities: []string{"critical"}}
+	assert.True(t, alertCritical.alertsFor([]Check{search, payments}))
+	assert.False(t, alertCritical.alertsFor([]Check{search}))
+	assert.True(t, Notification{}.alertsFor([]Check{search}))
+}
+
+func writeTempFile(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "insantus_unittest")
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = f.WriteString(content)
+	require.NoError(t, err)
+	t.Cleanup(func() { os.Remove(f.Name()) })
+	return f.Name()
+}
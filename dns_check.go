@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/pkg/errors"
+)
+
+var dnsRecordTypes = map[string]uint16{
+	"A":     dns.TypeA,
+	"AAAA":  dns.TypeAAAA,
+	"CNAME": dns.TypeCNAME,
+	"MX":    dns.TypeMX,
+	"TXT":   dns.TypeTXT,
+	"SRV":   dns.TypeSRV,
+}
+
+type DnsCheck struct {
+	environmentId string
+	checkId       string
+	name          string
+	timeout       time.Duration
+	resolver      string
+	protocol      string
+	record        string
+	recordType    uint16
+	expect        []string
+	minAnswers    int
+	maxLatency    time.Duration
+}
+
+func NewDnsCheck(environmentId, checkId, name string, timeout time.Duration, params map[string]string) (*DnsCheck, error) {
+	c := &DnsCheck{
+		environmentId: environmentId,
+		checkId:       checkId,
+		name:          name,
+		resolver:      params["resolver"],
+		protocol:      params["protocol"],
+		record:        params["record"],
+		minAnswers:    1,
+	}
+
+	if c.record == "" {
+		return nil, errors.New("missing record")
+	}
+
+	recordType := strings.ToUpper(params["type"])
+	if recordType == "" {
+		recordType = "A"
+	}
+	var exist bool
+	c.recordType, exist = dnsRecordTypes[recordType]
+	if !exist {
+		return nil, fmt.Errorf("record type not supported: %v", recordType)
+	}
+
+	if c.resolver == "" {
+		resolvConf, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+		if err != nil || len(resolvConf.Servers) == 0 {
+			return nil, errors.New("missing resolver and no nameserver found in /etc/resolv.conf")
+		}
+		c.resolver = net.JoinHostPort(resolvConf.Servers[0], resolvConf.Port)
+	} else if _, _, err := net.SplitHostPort(c.resolver); err != nil {
+		c.resolver = net.JoinHostPort(c.resolver, "53")
+	}
+
+	if c.protocol == "" {
+		c.protocol = "udp"
+	}
+
+	if e, exist := params["expect"]; exist {
+		for _, v := range strings.Split(e, ",") {
+			c.expect = append(c.expect, normalizeDnsValue(v))
+		}
+	}
+
+	if m, exist := params["minAnswers"]; exist {
+		minAnswers, err := strconv.Atoi(m)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing minAnswers")
+		}
+		c.minAnswers = minAnswers
+	}
+
+	if t, exist := params["maxLatency"]; exist {
+		d, err := time.ParseDuration(t)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing maxLatency")
+		}
+		c.maxLatency = d
+	}
+
+	d, err := checkTimeout(timeout, params)
+	if err != nil {
+		return nil, err
+	}
+	c.timeout = d
+
+	return c, nil
+}
+
+func (c *DnsCheck) Check(ctx context.Context) []Result {
+	mainResult := NewResult(c.environmentId, c.checkId, c.name)
+
+	mainResult.Status, mainResult.Message, mainResult.Detail = c.execute(ctx)
+
+	mainResult.Duration = int(time.Since(mainResult.Timestamp) / time.Millisecond)
+
+	results := []Result{mainResult}
+
+	return results
+}
+
+func (c *DnsCheck) execute(ctx context.Context) (status, message, detail string) {
+	client := &dns.Client{
+		Net:     c.protocol,
+		Timeout: c.timeout,
+	}
+
+	query := &dns.Msg{}
+	query.SetQuestion(dns.Fqdn(c.record), c.recordType)
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, rtt, err := client.ExchangeContext(ctx, query, c.resolver)
+	if err != nil {
+		return StatusDown, err.Error(), ""
+	}
+
+	if resp.Rcode != dns.RcodeSuccess {
+		return StatusDown, fmt.Sprintf("dns query for %v %v failed: %v", dns.TypeToString[c.recordType], c.record, dns.RcodeToString[resp.Rcode]), ""
+	}
+
+	answers := dnsAnswerValues(resp.Answer, c.recordType)
+	detail = strings.Join(answers, "\n")
+
+	if len(answers) < c.minAnswers {
+		return StatusDown, fmt.Sprintf("got %v %v records for %v (expected at least %v)", len(answers), dns.TypeToString[c.recordType], c.record, c.minAnswers), detail
+	}
+
+	for _, e := range c.expect {
+		if !contains(answers, e) {
+			return StatusDown, fmt.Sprintf("missing %v record %q for %v", dns.TypeToString[c.recordType], e, c.record), detail
+		}
+	}
+
+	if c.maxLatency != 0 && rtt > c.maxLatency {
+		return StatusDown, fmt.Sprintf("dns resolution took %v (max %v)", rtt, c.maxLatency), detail
+	}
+
+	return StatusUp, "", ""
+}
+
+// dnsAnswerValues returns the values of all answer records of the requested type
+// in a normalized string form: the address for A/AAAA, the target name for CNAME,
+// the mail host for MX, the joined text for TXT and target:port for SRV.
+func dnsAnswerValues(answers []dns.RR, recordType uint16) []string {
+	values := []string{}
+	for _, rr := range answers {
+		if rr.Header().Rrtype != recordType {
+			continue
+		}
+		var v string
+		switch r := rr.(type) {
+		case *dns.A:
+			v = r.A.String()
+		case *dns.AAAA:
+			v = r.AAAA.String()
+		case *dns.CNAME:
+			v = r.Target
+		case *dns.MX:
+			v = r.Mx
+		case *dns.TXT:
+			v = strings.Join(r.Txt, "")
+		case *dns.SRV:
+			v = fmt.Sprintf("%v:%v", strings.TrimSuffix(r.Target, "."), r.Port)
+		}
+		values = append(values, normalizeDnsValue(v))
+	}
+	return values
+}
+
+func normalizeDnsValue(v string) string {
+	return strings.TrimSuffix(strings.TrimSpace(v), ".")
+}
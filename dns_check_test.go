@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testZone = []string{
+	"www.example.org. 60 IN A 192.0.2.1",
+	"www.example.org. 60 IN A 192.0.2.2",
+	"www.example.org. 60 IN AAAA 2001:db8::1",
+	"alias.example.org. 60 IN CNAME www.example.org.",
+	"example.org. 60 IN MX 10 mx1.example.org.",
+	"example.org. 60 IN MX 20 mx2.example.org.",
+	"example.org. 60 IN TXT \"v=spf1 -all\"",
+	"_sip._tcp.example.org. 60 IN SRV 10 5 5060 sip.example.org.",
+	"slow.example.org. 60 IN A 192.0.2.3",
+}
+
+func Test_DnsCheck(t *testing.T) {
+	resolver := startDnsServer(t)
+
+	for _, test := range []struct {
+		name   string
+		params map[string]string
+
+		expectedStatus string
+	}{
+		{"a record", map[string]string{"record": "www.example.org"}, StatusUp},
+		{"a record expect", map[string]string{"record": "www.example.org", "expect": "192.0.2.2,192.0.2.1"}, StatusUp},
+		{"a record expect missing", map[string]string{"record": "www.example.org", "expect": "192.0.2.9"}, StatusDown},
+		{"a record min answers", map[string]string{"record": "www.example.org", "minAnswers": "2"}, StatusUp},
+		{"a record too few answers", map[string]string{"record": "www.example.org", "minAnswers": "3"}, StatusDown},
+		{"aaaa record", map[string]string{"record": "www.example.org", "type": "AAAA", "expect": "2001:db8::1"}, StatusUp},
+		{"cname record", map[string]string{"record": "alias.example.org", "type": "cname", "expect": "www.example.org."}, StatusUp},
+		{"mx record", map[string]string{"record": "example.org", "type": "MX", "expect": "mx1.example.org, mx2.example.org"}, StatusUp},
+		{"txt record", map[string]string{"record": "example.org", "type": "TXT", "expect": "v=spf1 -all"}, StatusUp},
+		{"srv record", map[string]string{"record": "_sip._tcp.example.org", "type": "SRV", "expect": "sip.example.org:5060"}, StatusUp},
+		{"nxdomain", map[string]string{"record": "missing.example.org"}, StatusDown},
+		{"no records of type", map[string]string{"record": "alias.example.org", "type": "MX"}, StatusDown},
+		{"latency", map[string]string{"record": "slow.example.org", "maxLatency": "20ms"}, StatusDown},
+		{"timeout", map[string]string{"record": "slow.example.org", "timeout": "20ms"}, StatusDown},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			check, err := NewDnsCheck(
+				"prod",
+				"test-check",
+				"test check",
+				0,
+				mergeParams(map[string]string{"resolver": resolver}, test.params),
+			)
+			require.NoError(t, err)
+
+			results := check.Check(context.Background())
+
+			require.Equal(t, 1, len(results))
+			assert.Equal(t, test.expectedStatus, results[0].Status, results[0].Message)
+		})
+	}
+}
+
+func Test_DnsCheck_InvalidParams(t *testing.T) {
+	_, err := NewDnsCheck("prod", "test-check", "test check", 0, map[string]string{"resolver": "127.0.0.1"})
+	assert.Error(t, err)
+
+	_, err = NewDnsCheck("prod", "test-check", "test check", 0, map[string]string{"resolver": "127.0.0.1", "record": "example.org", "type": "PTR"})
+	assert.Error(t, err)
+}
+
+// startDnsServer starts an in process dns server, serving the testZone
+// and returns its address.
+func startDnsServer(t *testing.T) string {
+	records := map[string][]dns.RR{}
+	for _, r := range testZone {
+		rr, err := dns.NewRR(r)
+		require.NoError(t, err)
+		records[rr.Header().Name] = append(records[rr.Header().Name], rr)
+	}
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	started := make(chan bool)
+	server := &dns.Server{
+		PacketConn:        pc,
+		NotifyStartedFunc: func() { close(started) },
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+			q := req.Question[0]
+			if q.Name == "slow.example.org." {
+				time.Sleep(50 * time.Millisecond)
+			}
+			m := &dns.Msg{}
+			m.SetReply(req)
+			rrs, exist := records[q.Name]
+			if !exist {
+				m.Rcode = dns.RcodeNameError
+			}
+			for _, rr := range rrs {
+				if rr.Header().Rrtype == q.Qtype {
+					m.Answer = append(m.Answer, rr)
+				}
+			}
+			w.WriteMsg(m)
+		}),
+	}
+	go server.ActivateAndServe()
+	<-started
+	t.Cleanup(func() { server.Shutdown() })
+
+	return pc.LocalAddr().String()
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ExecCheck runs a command, compatible to the nagios/monitoring-plugins
+// interface: the exit code is mapped to the status and the first line
+// of the output is taken as message.
+type ExecCheck struct {
+	environmentId string
+	checkId       string
+	name          string
+	timeout       time.Duration
+	command       string
+	args          []string
+	env           []string
+}
+
+func NewExecCheck(environmentId, checkId, name string, timeout time.Duration, params map[string]string) (*ExecCheck, error) {
+	c := &ExecCheck{
+		environmentId: environmentId,
+		checkId:       checkId,
+		name:          name,
+		command:       params["command"],
+	}
+
+	if c.command == "" {
+		return nil, errors.New("missing command")
+	}
+
+	args, err := splitArgs(params["args"])
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing args")
+	}
+	c.args = args
+
+	for k, v := range params {
+		if strings.HasPrefix(k, "env-") {
+			c.env = append(c.env, strings.TrimPrefix(k, "env-")+"="+v)
+		}
+	}
+
+	d, err := checkTimeout(timeout, params)
+	if err != nil {
+		return nil, err
+	}
+	c.timeout = d
+
+	return c, nil
+}
+
+func (c *ExecCheck) Check(ctx context.Context) []Result {
+	mainResult := NewResult(c.environmentId, c.checkId, c.name)
+
+	mainResult.Status, mainResult.Message, mainResult.Detail = c.execute(ctx)
+
+	mainResult.Duration = int(time.Since(mainResult.Timestamp) / time.Millisecond)
+
+	results := []Result{mainResult}
+
+	return results
+}
+
+func (c *ExecCheck) execute(ctx context.Context) (status, message, detail string) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, c.command, c.args...)
+	cmd.Env = append(os.Environ(), c.env...)
+	stdout := bytes.NewBufferString("")
+	stderr := bytes.NewBufferString("")
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// don't wait for child processes, still holding the output open after a timeout
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return StatusDown, fmt.Sprintf("timeout after %v running %v", c.timeout, c.command), stdout.String()
+	}
+	if ctx.Err() != nil {
+		return StatusDown, fmt.Sprintf("aborted running %v: %v", c.command, ctx.Err()), stdout.String()
+	}
+
+	exitCode := 0
+	if err != nil {
+		exitErr, isExitErr := err.(*exec.ExitError)
+		if !isExitErr {
+			return StatusDown, err.Error(), ""
+		}
+		exitCode = exitErr.ExitCode()
+	}
+
+	message, detail = parsePluginOutput(stdout.String())
+	if stderr.Len() > 0 {
+		detail = strings.TrimSpace(detail + "\n" + stderr.String())
+	}
+
+	switch exitCode {
+	case 0:
+		return StatusUp, message, detail
+	case 1:
+		return StatusDegraded, message, detail
+	case 2:
+		return StatusDown, message, detail
+	case 3:
+		if message == "" {
+			message = "UNKNOWN"
+		}
+		return StatusDown, message, detail
+	default:
+		return StatusDown, fmt.Sprintf("unexpected exit code %v: %v", exitCode, message), detail
+	}
+}
+
+// parsePluginOutput splits the output of a monitoring plugin.
+// The format is:
+//
+//	TEXT OUTPUT | OPTIONAL PERFDATA
+//	LONG TEXT LINE 1
+//	LONG TEXT LINE 2 | PERFDATA LINE 2
+//	PERFDATA LINE 3
+//
+// The text of the first line is returned as message, the long text
+// and the performance data as detail.
+func parsePluginOutput(output string) (message, detail string) {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+
+	perfdata := []string{}
+	first := strings.SplitN(lines[0], "|", 2)
+	message = strings.TrimSpace(first[0])
+	if len(first) == 2 && strings.TrimSpace(first[1]) != "" {
+		perfdata = append(perfdata, strings.TrimSpace(first[1]))
+	}
+
+	longText := []string{}
+	inPerfdata := false
+	for _, l := range lines[1:] {
+		if inPerfdata {
+			perfdata = append(perfdata, strings.TrimSpace(l))
+			continue
+		}
+		parts := strings.SplitN(l, "|", 2)
+		longText = append(longText, parts[0])
+		if len(parts) == 2 {
+			inPerfdata = true
+			perfdata = append(perfdata, strings.TrimSpace(parts[1]))
+		}
+	}
+
+	detail = strings.TrimSpace(strings.Join(longText, "\n"))
+	if len(perfdata) > 0 {
+		detail = strings.TrimSpace(detail + "\nperfdata: " + strings.Join(perfdata, " "))
+	}
+	return message, detail
+}
+
+// splitArgs splits a command line into its arguments,
+// respecting single and double quotes.
+func splitArgs(s string) ([]string, error) {
+	args := []string{}
+	current := bytes.NewBufferString("")
+	inArg := false
+	var quote rune
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", s)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ExecCheck(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		params map[string]string
+
+		expectedStatus  string
+		expectedMessage string
+		expectedDetail  string
+	}{
+		{
+			name:            "ok",
+			params:          map[string]string{"args": `-c 'echo "OK - all fine"'`},
+			expectedStatus:  StatusUp,
+			expectedMessage: "OK - all fine",
+		},
+		{
+			name:            "warning",
+			params:          map[string]string{"args": `-c 'echo "WARNING - disk 85%"; exit 1'`},
+			expectedStatus:  StatusDegraded,
+			expectedMessage: "WARNING - disk 85%",
+		},
+		{
+			name:            "critical",
+			params:          map[string]string{"args": `-c 'echo "CRITICAL - disk 99%"; exit 2'`},
+			expectedStatus:  StatusDown,
+			expectedMessage: "CRITICAL - disk 99%",
+		},
+		{
+			name:            "unknown",
+			params:          map[string]string{"args": `-c 'exit 3'`},
+			expectedStatus:  StatusDown,
+			expectedMessage: "UNKNOWN",
+		},
+		{
+			name:            "unexpected exit code",
+			params:          map[string]string{"args": `-c 'echo broken; exit 42'`},
+			expectedStatus:  StatusDown,
+			expectedMessage: "unexpected exit code 42: broken",
+		},
+		{
+			name:            "environment",
+			params:          map[string]string{"args": `-c 'echo "OK - $FOO"'`, "env-FOO": "bar"},
+			expectedStatus:  StatusUp,
+			expectedMessage: "OK - bar",
+		},
+		{
+			name: "perfdata and long text",
+			params: map[string]string{"args": `-c 'printf "%s\n" "DISK OK - free space: / 3326 MB | /=2643MB;5948;5958;0;5968" ` +
+				`"/ 15272 MB (77%);" "/boot 68 MB (69%); | /boot=68MB;88;93;0;98" "/home=69357MB;253404;253409;0;253414"'`},
+			expectedStatus:  StatusUp,
+			expectedMessage: "DISK OK - free space: / 3326 MB",
+			expectedDetail: "/ 15272 MB (77%);\n/boot 68 MB (69%);\n" +
+				"perfdata: /=2643MB;5948;5958;0;5968 /boot=68MB;88;93;0;98 /home=69357MB;253404;253409;0;253414",
+		},
+		{
+			name:            "stderr",
+			params:          map[string]string{"args": `-c 'echo "CRITICAL"; echo "some error" >&2; exit 2'`},
+			expectedStatus:  StatusDown,
+			expectedMessage: "CRITICAL",
+			expectedDetail:  "some error",
+		},
+		{
+			name:            "timeout",
+			params:          map[string]string{"args": `-c 'sleep 10'`, "timeout": "100ms"},
+			expectedStatus:  StatusDown,
+			expectedMessage: "timeout after 100ms running /bin/sh",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			check, err := NewExecCheck(
+				"prod",
+				"test-check",
+				"test check",
+				0,
+				mergeParams(map[string]string{"command": "/bin/sh"}, test.params),
+			)
+			require.NoError(t, err)
+
+			results := check.Check(context.Background())
+
+			require.Equal(t, 1, len(results))
+			assert.Equal(t, test.expectedStatus, results[0].Status)
+			assert.Equal(t, test.expectedMessage, results[0].Message)
+			assert.Equal(t, test.expectedDetail, results[0].Detail)
+		})
+	}
+}
+
+func Test_ExecCheck_CommandNotFound(t *testing.T) {
+	check, err := NewExecCheck("prod", "test-check", "test check", 0, map[string]string{"command": "/not/existing"})
+	require.NoError(t, err)
+
+	results := check.Check(context.Background())
+
+	require.Equal(t, 1, len(results))
+	assert.Equal(t, StatusDown, results[0].Status)
+}
+
+func Test_SplitArgs(t *testing.T) {
+	args, err := splitArgs(`-H localhost  -s "some string" -e 'it''s'`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-H", "localhost", "-s", "some string", "-e", "its"}, args)
+
+	_, err = splitArgs(`-s "unterminated`)
+	assert.Error(t, err)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -28,7 +29,7 @@ type HttpCheck struct {
 	header        map[string]string
 }
 
-func NewHttpCheck(environmentId, checkId, name string, params map[string]string) (*HttpCheck, error) {
+func NewHttpCheck(environmentId, checkId, name string, timeout time.Duration, params map[string]string) (*HttpCheck, error) {
 	c := &HttpCheck{
 		environmentId: environmentId,
 		checkId:       checkId,
@@ -48,15 +49,11 @@ func NewHttpCheck(environmentId, checkId, name string, params map[string]string)
 			return nil, err
 		}
 	}
-	if t, exist := params["timeout"]; exist {
-		d, err := time.ParseDuration(t)
-		if err != nil {
-			return nil, err
-		}
-		c.timeout = d
-	} else {
-		c.timeout = time.Second * 10
+	d, err := checkTimeout(timeout, params)
+	if err != nil {
+		return nil, err
 	}
+	c.timeout = d
 	for k, v := range params {
 		if strings.HasPrefix(k, "header-") {
 			c.header[strings.TrimPrefix(k, "header-")] = v
@@ -65,10 +62,10 @@ func NewHttpCheck(environmentId, checkId, name string, params map[string]string)
 	return c, nil
 }
 
-func (c *HttpCheck) Check() []Result {
+func (c *HttpCheck) Check(ctx context.Context) []Result {
 	mainResult := NewResult(c.environmentId, c.checkId, c.name)
 
-	mainResult.Status, mainResult.Message, mainResult.Detail = c.execute()
+	mainResult.Status, mainResult.Message, mainResult.Detail = c.execute(ctx)
 
 	mainResult.Duration = int(time.Since(mainResult.Timestamp) / time.Millisecond)
 
@@ -77,8 +74,11 @@ func (c *HttpCheck) Check() []Result {
 	return results
 }
 
-func (c *HttpCheck) execute() (status, message, detail string) {
-	r, err := http.NewRequest("GET", c.url, nil)
+func (c *HttpCheck) execute(ctx context.Context) (status, message, detail string) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	r, err := http.NewRequestWithContext(ctx, "GET", c.url, nil)
 
 	if err != nil {
 		return StatusDown, err.Error(), ""
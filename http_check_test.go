@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -123,10 +124,11 @@ func Test_HttpCheck(t *testing.T) {
 				"prod",
 				"test-check",
 				"test check",
+				0,
 				mergeParams(map[string]string{"url": server.URL + "/health"}, test.params),
 			)
 
-			results := check.Check()
+			results := check.Check(context.Background())
 
 			require.Equal(t, 1, len(results))
 			assert.Equal(t, test.expectedStatus, results[0].Status)
@@ -1,42 +1,73 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
 )
 
 type HttpServer struct {
-	cfg   *Config
-	store *Store
+	cfg        *Config
+	store      *Store
+	metrics    *Metrics
+	httpServer *http.Server
 }
 
-func NewHttpServer(cfg *Config, store *Store) *HttpServer {
-	return &HttpServer{
-		cfg:   cfg,
-		store: store,
+func NewHttpServer(cfg *Config, store *Store, metrics *Metrics) *HttpServer {
+	server := &HttpServer{
+		cfg:     cfg,
+		store:   store,
+		metrics: metrics,
 	}
+	server.httpServer = &http.Server{
+		Addr:    cfg.Listen,
+		Handler: server.router(),
+	}
+	return server
 }
 
-func (server *HttpServer) Start() {
-	log.Printf("starting http server at: %v\n", server.cfg.Listen)
-
+func (server *HttpServer) router() http.Handler {
 	router := mux.NewRouter()
 	router.HandleFunc("/api/environments", server.GetEnvironments)
 	router.HandleFunc("/api/environments/{env}", server.GetEnvironment)
+	router.HandleFunc("/api/environments/{env}/maintenance", server.GetMaintenance).Methods("GET")
+	router.HandleFunc("/api/environments/{env}/maintenance", server.CreateMaintenance).Methods("POST")
+	router.HandleFunc("/api/environments/{env}/maintenance/{id}/end", server.EndMaintenance).Methods("POST")
+	router.HandleFunc("/api/environments/{env}/notifications/failures", server.GetNotificationFailures)
+	router.HandleFunc("/api/environments/{env}/downtimes/{id}/ack", server.AckDowntime).Methods("POST")
+	router.HandleFunc("/api/environments/{env}/downtimes/{id}/comment", server.CommentDowntime).Methods("POST")
+	router.HandleFunc("/api/environments/{env}/uptime", server.GetUptime)
+	router.HandleFunc("/api/environments/{env}/checks/{check}/uptime", server.GetCheckUptime)
+	router.HandleFunc("/api/environments/{env}/checks/{check}/history", server.GetHistory)
+	router.HandleFunc("/api/environments/{env}/checks/{check}/results", server.GetResults)
+	router.HandleFunc("/api/environments/{env}/downtimes", server.GetDowntimes).Methods("GET")
 	router.HandleFunc("/api/results/{id}", server.GetResult)
+	router.Handle("/metrics", server.metrics.Handler())
 	router.Handle(`/{path:[a-zA-Z0-9=\-\/.]*}`, http.FileServer(http.Dir(server.cfg.Static)))
+	return router
+}
 
-	err := http.ListenAndServe(server.cfg.Listen, router)
-	if err != nil {
+func (server *HttpServer) Start() {
+	log.Printf("starting http server at: %v\n", server.cfg.Listen)
+
+	err := server.httpServer.ListenAndServe()
+	if err != nil && err != http.ErrServerClosed {
 		log.Fatalf("error starting http service %v\n", err)
 	}
 }
 
+// Shutdown stops the http server, after all active requests are finished.
+func (server *HttpServer) Shutdown(ctx context.Context) error {
+	return server.httpServer.Shutdown(ctx)
+}
+
 func (server *HttpServer) GetResult(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
@@ -60,7 +91,7 @@ func (server *HttpServer) GetResult(w http.ResponseWriter, r *http.Request) {
 func (server *HttpServer) GetEnvironments(w http.ResponseWriter, r *http.Request) {
 	overallStatus := StatusUp
 	response := map[string]interface{}{}
-	for _, env := range server.cfg.Environments {
+	for _, env := range server.cfg.Envs() {
 		envInfo := map[string]interface{}{
 			"id":      env.Id,
 			"name":    env.Name,
@@ -72,13 +103,16 @@ func (server *HttpServer) GetEnvironments(w http.ResponseWriter, r *http.Request
 			errorResponse(w, err)
 			return
 		}
-		good, bad := store.CountGoodAndBad(status)
+		good, bad, maintenance := store.CountGoodAndBad(status)
 		envInfo["good"] = good
 		envInfo["bad"] = bad
+		envInfo["maintenance"] = maintenance
 
 		if bad > 0 {
 			envInfo["status"] = StatusDown
 			overallStatus = StatusDown
+		} else if maintenance > 0 {
+			envInfo["status"] = StatusMaintenance
 		} else {
 			envInfo["status"] = StatusUp
 		}
@@ -98,17 +132,39 @@ func (server *HttpServer) GetEnvironment(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	downtimes, err := server.store.Downtimes(env, 0, 30)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+	acknowledged := map[string]bool{}
+	for _, d := range downtimes {
+		if !d.Recovered && d.Acknowledged {
+			acknowledged[d.Check] = true
+		}
+	}
+
+	envConfig, _ := server.cfg.EnvById(env)
+
 	overallStatus := StatusUp
 	checks := []map[string]interface{}{}
 	for _, s := range status {
-		if s.Status != StatusUp {
+		checkStatus := s.Status
+		if s.Status != StatusUp && s.Maintenance {
+			checkStatus = StatusMaintenance
+			if overallStatus == StatusUp {
+				overallStatus = StatusMaintenance
+			}
+		} else if s.Status != StatusUp {
 			overallStatus = StatusDown
 		}
 
 		info := map[string]interface{}{
 			"check":        s.Check,
 			"name":         s.Name,
-			"status":       s.Status,
+			"status":       checkStatus,
+			"maintenance":  s.Maintenance,
+			"acknowledged": acknowledged[s.Check],
 			"message":      s.Message,
 			"duration":     s.Duration,
 			"lastResultId": s.LastResultId,
@@ -116,6 +172,11 @@ func (server *HttpServer) GetEnvironment(w http.ResponseWriter, r *http.Request)
 			"sinceCheck":   sinceMs(s.Updated),
 		}
 
+		if c, exist := envConfig.checkById(s.Check); exist {
+			info["severity"] = c.severity()
+			info["team"] = c.Team
+		}
+
 		if s.Detail != "" {
 			jsonDetails := map[string]interface{}{}
 			err := json.Unmarshal([]byte(s.Detail), &jsonDetails)
@@ -127,21 +188,430 @@ func (server *HttpServer) GetEnvironment(w http.ResponseWriter, r *http.Request)
 		checks = append(checks, info)
 	}
 
-	downtimes, err := server.store.Downtimes(env)
+	windows, err := server.store.MaintenanceWindows(env, time.Now())
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	failures, err := server.store.DeliveryFailures(env, 10)
 	if err != nil {
 		errorResponse(w, err)
 		return
 	}
 
 	response := map[string]interface{}{
-		"status":    overallStatus,
-		"checks":    checks,
-		"downtimes": downtimes,
+		"status":               overallStatus,
+		"checks":               checks,
+		"downtimes":            downtimes,
+		"maintenance":          windows,
+		"notificationFailures": failures,
+	}
+
+	jsonReponse(w, response)
+}
+
+// GetMaintenance lists the active and upcoming maintenance windows of the environment.
+func (server *HttpServer) GetMaintenance(w http.ResponseWriter, r *http.Request) {
+	env := mux.Vars(r)["env"]
+	if _, exist := server.cfg.EnvById(env); !exist {
+		w.WriteHeader(404)
+		return
+	}
+
+	windows, err := server.store.MaintenanceWindows(env, time.Now())
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+	jsonReponse(w, windows)
+}
+
+type maintenanceRequest struct {
+	Name     string    `json:"name"`
+	Checks   []string  `json:"checks"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Duration string    `json:"duration"`
+	Comment  string    `json:"comment"`
+}
+
+// CreateMaintenance creates a maintenance window for the environment or some of its checks.
+// The window starts now, if no start is given and lasts until the end or for the duration.
+func (server *HttpServer) CreateMaintenance(w http.ResponseWriter, r *http.Request) {
+	envId := mux.Vars(r)["env"]
+	env, exist := server.cfg.EnvById(envId)
+	if !exist {
+		w.WriteHeader(404)
+		return
+	}
+
+	req := maintenanceRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		badRequestResponse(w)
+		return
+	}
+
+	window := &MaintenanceWindow{
+		Environment: envId,
+		Name:        req.Name,
+		Checks:      req.Checks,
+		Start:       req.Start,
+		End:         req.End,
+		Comment:     req.Comment,
+	}
+	if window.Checks == nil {
+		window.Checks = []string{}
+	}
+	if window.Start.IsZero() {
+		window.Start = time.Now().UTC()
+	}
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			badRequestResponse(w)
+			return
+		}
+		window.End = window.Start.Add(d)
+	}
+	if !window.End.After(window.Start) {
+		badRequestResponse(w)
+		return
+	}
+	for _, c := range window.Checks {
+		if !env.hasCheck(c) {
+			badRequestResponse(w)
+			return
+		}
+	}
+
+	err = server.store.CreateMaintenanceWindow(window)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(201)
+	jsonReponse(w, window)
+}
+
+// EndMaintenance ends a maintenance window, which was created by the api.
+func (server *HttpServer) EndMaintenance(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		badRequestResponse(w)
+		return
+	}
+
+	window, found, err := server.store.EndMaintenanceWindow(vars["env"], id, time.Now())
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+	if !found {
+		w.WriteHeader(404)
+		return
+	}
+	jsonReponse(w, window)
+}
+
+// GetNotificationFailures lists the failed deliveries of notifications,
+// which are retried or given up.
+func (server *HttpServer) GetNotificationFailures(w http.ResponseWriter, r *http.Request) {
+	failures, err := server.store.DeliveryFailures(mux.Vars(r)["env"], 100)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+	jsonReponse(w, failures)
+}
+
+type downtimeNoteRequest struct {
+	By   string `json:"by"`
+	Note string `json:"note"`
+}
+
+// AckDowntime acknowledges a downtime, so that it is not notified any more.
+// The request body contains the name of the acknowledging person and an optional note.
+func (server *HttpServer) AckDowntime(w http.ResponseWriter, r *http.Request) {
+	server.updateDowntime(w, r, server.store.AckDowntime)
+}
+
+// CommentDowntime sets the comment of a downtime.
+func (server *HttpServer) CommentDowntime(w http.ResponseWriter, r *http.Request) {
+	server.updateDowntime(w, r, server.store.CommentDowntime)
+}
+
+func (server *HttpServer) updateDowntime(w http.ResponseWriter, r *http.Request,
+	update func(environment string, id int, by, note string, t time.Time) (*Downtime, bool, error)) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		badRequestResponse(w)
+		return
+	}
+
+	req := downtimeNoteRequest{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil || req.By == "" {
+		badRequestResponse(w)
+		return
+	}
+
+	d, found, err := update(vars["env"], id, req.By, req.Note, time.Now())
+	if err != nil {
+		errorResponse(w, err)
+		return
 	}
+	if !found {
+		w.WriteHeader(404)
+		return
+	}
+	jsonReponse(w, d)
+}
+
+// GetUptime returns the availability of the environment and its checks.
+func (server *HttpServer) GetUptime(w http.ResponseWriter, r *http.Request) {
+	report, ok := server.uptime(w, r)
+	if ok {
+		jsonReponse(w, report)
+	}
+}
+
+// GetCheckUptime returns the availability of one check.
+func (server *HttpServer) GetCheckUptime(w http.ResponseWriter, r *http.Request) {
+	report, ok := server.uptime(w, r)
+	if !ok {
+		return
+	}
+	check := mux.Vars(r)["check"]
+	for _, u := range report.Checks {
+		if u.Check == check {
+			jsonReponse(w, u)
+			return
+		}
+	}
+	w.WriteHeader(404)
+}
 
+// uptime calculates the uptime report for the time range of the request, which defaults to the last 30 days.
+// On false, the response was already written.
+func (server *HttpServer) uptime(w http.ResponseWriter, r *http.Request) (*UptimeReport, bool) {
+	env := mux.Vars(r)["env"]
+	if _, exist := server.cfg.EnvById(env); !exist {
+		w.WriteHeader(404)
+		return nil, false
+	}
+
+	from, to, err := timeRange(r, 30*24*time.Hour)
+	if err != nil {
+		badRequestResponse(w)
+		return nil, false
+	}
+	excludeMaintenance := r.URL.Query().Get("excludeMaintenance") == "true"
+
+	report, err := server.store.Uptime(env, from, to, excludeMaintenance)
+	if err != nil {
+		errorResponse(w, err)
+		return nil, false
+	}
+	return report, true
+}
+
+// maxHistoryBuckets limits the rollups of one history request.
+var maxHistoryBuckets = 10000
+
+// GetHistory returns the rollups of the check for the time range, which defaults to the last 90 days.
+// Without a resolution, the finest one with at most 500 buckets is chosen.
+func (server *HttpServer) GetHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	env, exist := server.cfg.EnvById(vars["env"])
+	if !exist || !env.hasCheck(vars["check"]) {
+		w.WriteHeader(404)
+		return
+	}
+
+	from, to, err := timeRange(r, 90*24*time.Hour)
+	if err != nil {
+		badRequestResponse(w)
+		return
+	}
+
+	resolution := r.URL.Query().Get("resolution")
+	if resolution == "" {
+		resolution = RollupDay
+		for _, res := range []string{RollupHour, RollupMinute} {
+			if to.Sub(from)/rollupResolutions[res] <= 500 {
+				resolution = res
+			}
+		}
+	}
+	d, exist := rollupResolutions[resolution]
+	if !exist || to.Sub(from)/d > time.Duration(maxHistoryBuckets) {
+		badRequestResponse(w)
+		return
+	}
+
+	rollups, err := server.store.History(env.Id, vars["check"], resolution, from, to)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+	jsonReponse(w, map[string]interface{}{
+		"resolution": resolution,
+		"from":       from,
+		"to":         to,
+		"buckets":    rollups,
+	})
+}
+
+// GetResults returns a page of the results of the check, the newest first.
+// The results are filtered by the time range, which defaults to the last 24 hours, and by the
+// comma separated statuses. The detail is only included with includeDetail=true.
+// The next page is requested with the returned nextCursor.
+func (server *HttpServer) GetResults(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	env, exist := server.cfg.EnvById(vars["env"])
+	if !exist || !env.hasCheck(vars["check"]) {
+		w.WriteHeader(404)
+		return
+	}
+
+	query := r.URL.Query()
+	q := ResultQuery{IncludeDetail: query.Get("includeDetail") == "true"}
+	var err error
+	q.From, q.To, err = timeRange(r, 24*time.Hour)
+	if err != nil {
+		badRequestResponse(w)
+		return
+	}
+	q.Limit, err = pageLimit(r, 100, 1000)
+	if err != nil {
+		badRequestResponse(w)
+		return
+	}
+	if query.Get("cursor") != "" {
+		cursor, err := strconv.ParseUint(query.Get("cursor"), 10, 64)
+		if err != nil {
+			badRequestResponse(w)
+			return
+		}
+		q.Before = uint(cursor)
+	}
+	if query.Get("status") != "" {
+		for _, status := range strings.Split(query.Get("status"), ",") {
+			status = strings.ToUpper(strings.TrimSpace(status))
+			if status != StatusUp && status != StatusDegraded && status != StatusDown {
+				badRequestResponse(w)
+				return
+			}
+			q.Statuses = append(q.Statuses, status)
+		}
+	}
+
+	// one more result tells, if there is a next page
+	limit := q.Limit
+	q.Limit++
+	results, err := server.store.Results(env.Id, vars["check"], q)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+	response := map[string]interface{}{}
+	if len(results) > limit {
+		results = results[:limit]
+		response["nextCursor"] = strconv.Itoa(int(results[limit-1].Id))
+	}
+	response["results"] = results
+	jsonReponse(w, response)
+}
+
+// GetDowntimes returns a page of the downtimes of the environment, the open ones first and then the newest.
+// The next page is requested with the returned nextOffset.
+func (server *HttpServer) GetDowntimes(w http.ResponseWriter, r *http.Request) {
+	env := mux.Vars(r)["env"]
+	if _, exist := server.cfg.EnvById(env); !exist {
+		w.WriteHeader(404)
+		return
+	}
+
+	limit, err := pageLimit(r, 30, 1000)
+	if err != nil {
+		badRequestResponse(w)
+		return
+	}
+	offset := 0
+	if r.URL.Query().Get("offset") != "" {
+		offset, err = strconv.Atoi(r.URL.Query().Get("offset"))
+		if err != nil || offset < 0 {
+			badRequestResponse(w)
+			return
+		}
+	}
+
+	// one more downtime tells, if there is a next page
+	downtimes, err := server.store.Downtimes(env, offset, limit+1)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+	response := map[string]interface{}{}
+	if len(downtimes) > limit {
+		downtimes = downtimes[:limit]
+		response["nextOffset"] = offset + limit
+	}
+	response["downtimes"] = downtimes
 	jsonReponse(w, response)
 }
 
+// pageLimit parses the query parameter limit, which has to be between 1 and the max limit.
+func pageLimit(r *http.Request, defaultLimit, maxLimit int) (int, error) {
+	if r.URL.Query().Get("limit") == "" {
+		return defaultLimit, nil
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		return 0, err
+	}
+	if limit < 1 || limit > maxLimit {
+		return 0, errors.Errorf("the limit has to be between 1 and %v", maxLimit)
+	}
+	return limit, nil
+}
+
+// timeRange parses the query parameters from and to (RFC3339) of the request in UTC.
+// By default, the range ends now and starts the default duration before.
+func timeRange(r *http.Request, defaultDuration time.Duration) (from, to time.Time, err error) {
+	query := r.URL.Query()
+	now := time.Now().UTC()
+	to = now
+	if query.Get("to") != "" {
+		to, err = time.Parse(time.RFC3339, query.Get("to"))
+		if err != nil {
+			return
+		}
+		to = to.UTC()
+		if to.After(now) {
+			to = now
+		}
+	}
+	from = to.Add(-defaultDuration)
+	if query.Get("from") != "" {
+		from, err = time.Parse(time.RFC3339, query.Get("from"))
+		if err != nil {
+			return
+		}
+		from = from.UTC()
+	}
+	if !to.After(from) {
+		err = errors.New("the range has to end after its start")
+	}
+	return
+}
+
 func errorResponse(w http.ResponseWriter, err error) {
 	log.Printf("internal error occured %v\n", err)
 	w.Header().Set("Content-Type", "application/json")
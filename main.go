@@ -1,9 +1,16 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	_ "net/http/pprof"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
 )
 
 var store *Store
@@ -20,14 +27,42 @@ func main() {
 	if err != nil {
 		log.Fatalf("error opening database %v\n", err)
 	}
+	store.deliverInBackground()
 
 	resultCallback := make(chan []Result, 50)
-	startChecking(cfg, resultCallback)
-	httpServer := NewHttpServer(cfg, store)
+	runner := NewCheckRunner(cfg, resultCallback)
+	err = runner.Start()
+	if err != nil {
+		log.Fatalf("error starting checks %v\n", err)
+	}
+	metrics := NewMetrics(cfg, store, runner.QueueLength)
+	httpServer := NewHttpServer(cfg, store, metrics)
 	go httpServer.Start()
 
+	go waitForShutdown(cfg, runner)
+	go watchForReload(cfg, runner, store, metrics)
+
+	timersCtx, stopTimers := context.WithCancel(context.Background())
+	deliveriesDone := make(chan bool)
+	go func() {
+		runDeliveries(timersCtx, store, time.Minute)
+		close(deliveriesDone)
+	}()
+	timersDone := make(chan bool)
+	go func() {
+		runNotificationTimers(timersCtx, store, time.Minute)
+		close(timersDone)
+	}()
+	pruningDone := make(chan bool)
+	go func() {
+		runPruning(timersCtx, store, cfg.PruneInterval)
+		close(pruningDone)
+	}()
+
+	// runs until the runner closes the callback on shutdown
 	for results := range resultCallback {
 		for _, result := range results {
+			metrics.Observe(result)
 			err := store.InsertResult(result)
 			if err != nil {
 				log.Printf("error storing check result: %v\n", err)
@@ -39,6 +74,183 @@ func main() {
 			}
 		}
 	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	err = httpServer.Shutdown(ctx)
+	if err != nil {
+		log.Printf("error on http server shutdown: %v\n", err)
+	}
+
+	stopTimers()
+	<-timersDone
+	<-pruningDone
+	<-deliveriesDone
+
+	err = store.Close()
+	if err != nil {
+		log.Printf("error closing database: %v\n", err)
+	}
+	log.Println("shutdown complete")
+}
+
+// waitForShutdown stops the check runner on SIGINT or SIGTERM.
+// A second signal or the shutdown timeout aborts the running checks.
+func waitForShutdown(cfg *Config, runner *CheckRunner) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	sig := <-signals
+	log.Printf("received %v, shutting down\n", sig)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	runner.Stop(ctx)
+}
+
+// watchForReload reloads the configuration on SIGHUP and,
+// if polling is enabled, when one of the YAML files has changed.
+func watchForReload(cfg *Config, runner *CheckRunner, store *Store, metrics *Metrics) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	var poll <-chan time.Time
+	if cfg.ConfigPoll > 0 {
+		ticker := time.NewTicker(cfg.ConfigPoll)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
+	lastModified := configModTime(cfg)
+	for {
+		select {
+		case <-signals:
+			log.Println("received SIGHUP, reloading configuration")
+		case <-poll:
+			modified := configModTime(cfg)
+			if modified.Equal(lastModified) {
+				continue
+			}
+			lastModified = modified
+			log.Println("configuration changed, reloading")
+		}
+
+		err := reloadConfig(cfg, runner, store, metrics)
+		if err != nil {
+			log.Printf("error reloading configuration, keeping the current one: %v\n", err)
+			continue
+		}
+		log.Println("configuration reloaded")
+	}
+}
+
+// reloadConfig reads the YAML files and applies the changes.
+// The metrics of removed checks are deleted.
+func reloadConfig(cfg *Config, runner *CheckRunner, store *Store, metrics *Metrics) error {
+	envs, err := loadEnvironments(cfg.EnvironmentsPath, cfg.ChecksPath)
+	if err != nil {
+		return err
+	}
+
+	oldEnvs := cfg.Envs()
+	cfg.SetEnvs(envs)
+	err = store.updateChecks(cfg)
+	if err != nil {
+		cfg.SetEnvs(oldEnvs)
+		return errors.Wrap(err, "updating CheckSummaries")
+	}
+
+	err = runner.Reload(envs)
+	if err != nil {
+		return err
+	}
+	metrics.RemoveChecks(oldEnvs)
+	return nil
+}
+
+// runNotificationTimers sends the notifications, which are due by time
+// and not triggered by a new result, until the context is done.
+func runNotificationTimers(ctx context.Context, store *Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			err := store.checkForEscalations(now)
+			if err != nil {
+				log.Printf("error checking for escalations: %v\n", err)
+			}
+			err = store.checkForReminders(now)
+			if err != nil {
+				log.Printf("error checking for reminders: %v\n", err)
+			}
+		}
+	}
+}
+
+// runDeliveries sends the queued notifications, when new ones are enqueued
+// and every interval for the retries, until the context is done.
+func runDeliveries(ctx context.Context, store *Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-store.deliveryWakeup:
+		case <-ticker.C:
+		}
+		err := store.deliverNotifications(time.Now())
+		if err != nil {
+			log.Printf("error delivering notifications: %v\n", err)
+		}
+	}
+}
+
+// runPruning deletes the rows exceeding their retention every interval, until the context is done.
+// Without any retention, the rows are kept forever and nothing is pruned.
+func runPruning(ctx context.Context, store *Store, interval time.Duration) {
+	if interval <= 0 || !store.cfg.retentionEnabled() {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			p, err := store.prune(now)
+			if err != nil {
+				log.Printf("error pruning the database: %v\n", err)
+			}
+			if p.total() > 0 {
+				log.Printf("pruned %v results, %v downtimes, %v notifications and %v rollups\n",
+					p.results, p.downtimes, p.notifications, p.rollups)
+			}
+		}
+	}
+}
+
+func configModTime(cfg *Config) time.Time {
+	modified := time.Time{}
+	for _, path := range []string{cfg.EnvironmentsPath, cfg.ChecksPath} {
+		info, err := os.Stat(path)
+		if err == nil && info.ModTime().After(modified) {
+			modified = info.ModTime()
+		}
+	}
+	return modified
 }
 
 func profiler(cfg *Config) {
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/robfig/cron"
+)
+
+func (m Maintenance) validate(checkIds map[string]bool) error {
+	for _, c := range m.Checks {
+		if !checkIds[c] {
+			return fmt.Errorf("unknown check %v", c)
+		}
+	}
+
+	if m.Cron != "" {
+		if m.Duration <= 0 {
+			return errors.New("a recurring maintenance needs a duration")
+		}
+		_, _, err := m.schedule()
+		return err
+	}
+
+	if m.Start.IsZero() {
+		return errors.New("either start or cron is needed")
+	}
+	if !m.End.After(m.Start) && m.Duration <= 0 {
+		return errors.New("either an end after the start or a duration is needed")
+	}
+	return nil
+}
+
+// schedule parses the cron expression in the timezone of the maintenance.
+func (m Maintenance) schedule() (cron.Schedule, *time.Location, error) {
+	loc := time.Local
+	if m.Timezone != "" {
+		var err error
+		loc, err = time.LoadLocation(m.Timezone)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	schedule, err := cron.ParseStandard(m.Cron)
+	return schedule, loc, err
+}
+
+// window returns the occurrence of the maintenance, which is active at t or starts after t.
+// The second return value is false, if there is no such occurrence.
+func (m Maintenance) window(envId string, t time.Time) (*MaintenanceWindow, bool) {
+	w := &MaintenanceWindow{
+		Environment: envId,
+		Name:        m.Id,
+		Checks:      m.Checks,
+		Comment:     m.Comment,
+		Configured:  true,
+	}
+
+	if m.Cron == "" {
+		w.Start = m.Start
+		w.End = m.End
+		if !w.End.After(w.Start) {
+			w.End = m.Start.Add(m.Duration)
+		}
+		return w, t.Before(w.End)
+	}
+
+	schedule, loc, err := m.schedule()
+	if err != nil {
+		// the maintenance was validated on load
+		return nil, false
+	}
+	// the first start after t - duration is either active at t or the next one
+	w.Start = schedule.Next(t.Add(-m.Duration).In(loc))
+	w.End = w.Start.Add(m.Duration)
+	return w, !w.Start.IsZero()
+}
+
+// windows returns the occurrences of the maintenance, which overlap the range from to.
+func (m Maintenance) windows(envId string, from, to time.Time) []*MaintenanceWindow {
+	windows := []*MaintenanceWindow{}
+	t := from
+	for {
+		w, exist := m.window(envId, t)
+		if !exist || !w.Start.Before(to) {
+			return windows
+		}
+		windows = append(windows, w)
+		if m.Cron == "" {
+			return windows
+		}
+		t = w.End
+	}
+}
+
+// MaintenanceWindows returns the configured and the created maintenance windows
+// of the environment, which are active at t or start later.
+func (store *Store) MaintenanceWindows(environment string, t time.Time) ([]*MaintenanceWindow, error) {
+	windows := []*MaintenanceWindow{}
+	err := store.db.
+		Where(`environment = ? AND "end" > ?`, environment, t.UTC()).
+		Find(&windows).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	env, _ := store.cfg.EnvById(environment)
+	for _, m := range env.Maintenance {
+		if w, exist := m.window(environment, t); exist {
+			windows = append(windows, w)
+		}
+	}
+
+	sort.Slice(windows, func(i, j int) bool {
+		return windows[i].Start.Before(windows[j].Start)
+	})
+	return windows, nil
+}
+
+// maintenanceWindowsBetween returns the configured and the created maintenance windows
+// of the environment, which overlap the range from to.
+func (store *Store) maintenanceWindowsBetween(environment string, from, to time.Time) ([]*MaintenanceWindow, error) {
+	windows := []*MaintenanceWindow{}
+	err := store.db.
+		Where(`environment = ? AND start < ? AND "end" > ?`, environment, to.UTC(), from.UTC()).
+		Find(&windows).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	env, _ := store.cfg.EnvById(environment)
+	for _, m := range env.Maintenance {
+		windows = append(windows, m.windows(environment, from, to)...)
+	}
+	return windows, nil
+}
+
+// inMaintenance returns true, if the check is covered by an active maintenance window at t.
+func (store *Store) inMaintenance(environment, check string, t time.Time) (bool, error) {
+	windows, err := store.MaintenanceWindows(environment, t)
+	if err != nil {
+		return false, err
+	}
+	for _, w := range windows {
+		if w.activeAt(t) && w.covers(check) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// CreateMaintenanceWindow stores the window with its times in UTC, to be comparable with the other times.
+func (store *Store) CreateMaintenanceWindow(w *MaintenanceWindow) error {
+	w.Start = w.Start.UTC()
+	w.End = w.End.UTC()
+	return store.db.Create(w).Error
+}
+
+// EndMaintenanceWindow ends a created maintenance window at t.
+// A window, which has not started yet, is cancelled.
+func (store *Store) EndMaintenanceWindow(environment string, id int, t time.Time) (*MaintenanceWindow, bool, error) {
+	w := &MaintenanceWindow{}
+	err := store.db.
+		Where(`environment = ? AND id = ?`, environment, id).
+		First(w).
+		Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	t = t.UTC()
+	if w.End.After(t) {
+		w.End = t
+		if w.Start.After(t) {
+			w.Start = t
+		}
+		err = store.db.Save(w).Error
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	return w, true, nil
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Maintenance_Window(t *testing.T) {
+	start := time.Date(2026, 3, 10, 22, 0, 0, 0, time.UTC)
+
+	oneOff := Maintenance{Id: "migration", Start: start, Duration: time.Hour}
+	w, exist := oneOff.window("prod", start.Add(-time.Minute))
+	require.True(t, exist)
+	assert.Equal(t, start, w.Start)
+	assert.Equal(t, start.Add(time.Hour), w.End)
+	assert.False(t, w.activeAt(start.Add(-time.Minute)))
+	assert.True(t, w.activeAt(start))
+	assert.False(t, w.activeAt(start.Add(time.Hour)))
+
+	_, exist = oneOff.window("prod", start.Add(time.Hour))
+	assert.False(t, exist)
+
+	// every wednesday at 22:00 for 2 hours
+	weekly := Maintenance{Id: "deploy", Cron: "0 22 * * 3", Duration: 2 * time.Hour, Timezone: "UTC"}
+	for _, test := range []struct {
+		name          string
+		t             time.Time
+		expectedStart time.Time
+		active        bool
+	}{
+		{"before", start.Add(-time.Hour), time.Date(2026, 3, 11, 22, 0, 0, 0, time.UTC), false},
+		{"start", time.Date(2026, 3, 11, 22, 0, 0, 0, time.UTC), time.Date(2026, 3, 11, 22, 0, 0, 0, time.UTC), true},
+		{"within", time.Date(2026, 3, 11, 23, 59, 0, 0, time.UTC), time.Date(2026, 3, 11, 22, 0, 0, 0, time.UTC), true},
+		{"after", time.Date(2026, 3, 12, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 18, 22, 0, 0, 0, time.UTC), false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			w, exist := weekly.window("prod", test.t)
+			require.True(t, exist)
+			assert.True(t, test.expectedStart.Equal(w.Start))
+			assert.Equal(t, test.active, w.activeAt(test.t))
+		})
+	}
+}
+
+func Test_Maintenance_Windows(t *testing.T) {
+	from := time.Date(2026, 3, 11, 23, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 25, 22, 0, 0, 0, time.UTC)
+
+	weekly := Maintenance{Id: "deploy", Cron: "0 22 * * 3", Duration: 2 * time.Hour, Timezone: "UTC"}
+	windows := weekly.windows("prod", from, to)
+	require.Equal(t, 2, len(windows))
+	assert.True(t, time.Date(2026, 3, 11, 22, 0, 0, 0, time.UTC).Equal(windows[0].Start))
+	assert.True(t, time.Date(2026, 3, 18, 22, 0, 0, 0, time.UTC).Equal(windows[1].Start))
+
+	oneOff := Maintenance{Id: "migration", Start: from, Duration: time.Hour}
+	assert.Equal(t, 1, len(oneOff.windows("prod", from, to)))
+	assert.Equal(t, 0, len(oneOff.windows("prod", to, to.Add(time.Hour))))
+}
+
+func Test_Maintenance_Validate(t *testing.T) {
+	checkIds := map[string]bool{"check1": true}
+	now := time.Now()
+
+	assert.NoError(t, Maintenance{Start: now, End: now.Add(time.Hour)}.validate(checkIds))
+	assert.NoError(t, Maintenance{Cron: "0 22 * * 3", Duration: time.Hour, Checks: []string{"check1"}}.validate(checkIds))
+
+	assert.Error(t, Maintenance{}.validate(checkIds))
+	assert.Error(t, Maintenance{Start: now}.validate(checkIds))
+	assert.Error(t, Maintenance{Cron: "0 22 * * 3"}.validate(checkIds))
+	assert.Error(t, Maintenance{Cron: "every day", Duration: time.Hour}.validate(checkIds))
+	assert.Error(t, Maintenance{Cron: "0 22 * * 3", Duration: time.Hour, Timezone: "Mars/Olympus"}.validate(checkIds))
+	assert.Error(t, Maintenance{Cron: "0 22 * * 3", Duration: time.Hour, Checks: []string{"check9"}}.validate(checkIds))
+}
+
+func Test_Store_Maintenance_SuppressesNotifications(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Environments[0].Maintenance = []Maintenance{
+		{Id: "deploy", Checks: []string{"check1"}, Start: time.Now().Add(-time.Minute), Duration: time.Hour},
+	}
+	notifyMock := &NotifyMock{}
+	store, err := NewStore(cfg, notifyMock)
+	require.NoError(t, err)
+	defer store.Close()
+
+	require.NoError(t, store.InsertResult(downResult("check1")))
+	require.NoError(t, store.InsertResult(downResult("check1")))
+	notifyMock.AssertNoNotifications(t)
+
+	// check2 is not covered by the window
+	require.NoError(t, store.InsertResult(downResult("check2")))
+	require.NoError(t, store.InsertResult(downResult("check2")))
+	require.Equal(t, 1, len(notifyMock.downs))
+	assert.Equal(t, "check2", notifyMock.downs[0].Check)
+
+	s, err := store.Status("testEnv")
+	require.NoError(t, err)
+	assert.True(t, s[0].Maintenance)
+	assert.False(t, s[1].Maintenance)
+
+	res, _, err := store.Result(int(s[0].LastResultId))
+	require.NoError(t, err)
+	assert.True(t, res.Maintenance)
+
+	good, bad, maintenance := store.CountGoodAndBad(s)
+	assert.Equal(t, 0, good)
+	assert.Equal(t, 1, bad)
+	assert.Equal(t, 1, maintenance)
+
+	// after the maintenance, the still open downtime is notified
+	notifyMock.reset()
+	cfg.Environments[0].Maintenance = nil
+	require.NoError(t, store.InsertResult(downResult("check1")))
+	require.Equal(t, 1, len(notifyMock.downs))
+	assert.Equal(t, "check1", notifyMock.downs[0].Check)
+	assert.True(t, notifyMock.downs[0].Maintenance)
+}
+
+func Test_Store_Maintenance_CreateAndEnd(t *testing.T) {
+	cfg := testConfig(t)
+	notifyMock := &NotifyMock{}
+	store, err := NewStore(cfg, notifyMock)
+	require.NoError(t, err)
+	defer store.Close()
+
+	now := time.Now()
+	w := &MaintenanceWindow{
+		Environment: "testEnv",
+		Name:        "deploy",
+		Start:       now.Add(-time.Minute),
+		End:         now.Add(time.Hour),
+	}
+	require.NoError(t, store.CreateMaintenanceWindow(w))
+
+	windows, err := store.MaintenanceWindows("testEnv", now)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(windows))
+	assert.Equal(t, "deploy", windows[0].Name)
+	assert.Equal(t, []string{}, windows[0].Checks)
+
+	require.NoError(t, store.InsertResult(downResult("check1")))
+	require.NoError(t, store.InsertResult(downResult("check2")))
+	require.NoError(t, store.InsertResult(downResult("check1")))
+	require.NoError(t, store.InsertResult(downResult("check2")))
+	notifyMock.AssertNoNotifications(t)
+
+	_, found, err := store.EndMaintenanceWindow("otherEnv", int(w.Id), time.Now())
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	ended, found, err := store.EndMaintenanceWindow("testEnv", int(w.Id), time.Now())
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.False(t, ended.activeAt(time.Now()))
+
+	windows, err = store.MaintenanceWindows("testEnv", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(windows))
+
+	require.NoError(t, store.InsertResult(downResult("check1")))
+	assert.Equal(t, 2, len(notifyMock.downs))
+}
+
+func Test_Store_Maintenance_LocalTimezone(t *testing.T) {
+	defer localTimezone(time.FixedZone("JST", 9*60*60))()
+
+	store, err := NewStore(testConfig(t), &NotifyMock{})
+	require.NoError(t, err)
+	defer store.Close()
+
+	// created by the api with times in UTC and in the local time of the server
+	now := time.Now()
+	require.NoError(t, store.CreateMaintenanceWindow(&MaintenanceWindow{
+		Environment: "testEnv", Name: "utc", Checks: []string{"check1"}, Start: now.UTC().Add(-time.Hour), End: now.UTC().Add(time.Hour),
+	}))
+	require.NoError(t, store.CreateMaintenanceWindow(&MaintenanceWindow{
+		Environment: "testEnv", Name: "mixed", Checks: []string{"check2"}, Start: now, End: now.UTC().Add(time.Hour),
+	}))
+
+	for _, check := range []string{"check1", "check2"} {
+		active, err := store.inMaintenance("testEnv", check, time.Now())
+		require.NoError(t, err)
+		assert.True(t, active, check)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	checkStatusDesc = prometheus.NewDesc(
+		"insantus_check_status",
+		"Current status of the check, 1 for the active status.",
+		[]string{"environment", "check", "status"}, nil)
+	checkMaintenanceDesc = prometheus.NewDesc(
+		"insantus_check_maintenance",
+		"1, if the last result of the check was within a maintenance window.",
+		[]string{"environment", "check"}, nil)
+	openDowntimesDesc = prometheus.NewDesc(
+		"insantus_open_downtimes",
+		"Number of downtimes, which are not recovered.",
+		[]string{"environment"}, nil)
+)
+
+// Metrics exports the state of the checks in the prometheus format.
+type Metrics struct {
+	cfg        *Config
+	registry   *prometheus.Registry
+	duration   *prometheus.HistogramVec
+	executions *prometheus.CounterVec
+	certExpiry *prometheus.GaugeVec
+}
+
+// NewMetrics creates the metrics. The status of the checks and the open downtimes
+// are read from the store on each scrape.
+func NewMetrics(cfg *Config, store *Store, queueLength func() int) *Metrics {
+	m := &Metrics{
+		cfg:      cfg,
+		registry: prometheus.NewRegistry(),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "insantus_check_duration_seconds",
+			Help:    "Duration of the check executions.",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 12),
+		}, []string{"environment", "check"}),
+		executions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "insantus_check_executions_total",
+			Help: "Number of check executions by result status.",
+		}, []string{"environment", "check", "status"}),
+		certExpiry: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "insantus_cert_expiry_timestamp_seconds",
+			Help: "Expiry of the certificate, checked by a cert check, as unix timestamp.",
+		}, []string{"environment", "check"}),
+	}
+
+	m.registry.MustRegister(
+		m.duration,
+		m.executions,
+		m.certExpiry,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "insantus_check_queue_length",
+			Help: "Number of checks waiting for a free worker.",
+		}, func() float64 {
+			return float64(queueLength())
+		}),
+		&storeCollector{cfg: cfg, store: store},
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Observe records the execution of a check.
+// Results of checks, which were removed from the configuration in the meantime, are ignored.
+func (m *Metrics) Observe(result Result) {
+	env, _ := m.cfg.EnvById(result.Environment)
+	if !env.hasCheck(result.Check) {
+		return
+	}
+	m.duration.WithLabelValues(result.Environment, result.Check).Observe(float64(result.Duration) / 1000)
+	m.executions.WithLabelValues(result.Environment, result.Check, result.Status).Inc()
+	if !result.CertExpiry.IsZero() {
+		m.certExpiry.WithLabelValues(result.Environment, result.Check).Set(float64(result.CertExpiry.Unix()))
+	}
+}
+
+// RemoveChecks deletes the series of the checks in oldEnvs,
+// which are not part of the current configuration anymore.
+func (m *Metrics) RemoveChecks(oldEnvs []Env) {
+	for _, old := range oldEnvs {
+		env, _ := m.cfg.EnvById(old.Id)
+		for _, c := range old.Checks {
+			if env.hasCheck(c.Id) {
+				continue
+			}
+			m.duration.DeleteLabelValues(old.Id, c.Id)
+			m.certExpiry.DeleteLabelValues(old.Id, c.Id)
+			for _, status := range []string{StatusUp, StatusDegraded, StatusDown} {
+				m.executions.DeleteLabelValues(old.Id, c.Id, status)
+			}
+		}
+	}
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// storeCollector collects the current status of the checks from the store.
+type storeCollector struct {
+	cfg   *Config
+	store *Store
+}
+
+func (c *storeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- checkStatusDesc
+	ch <- checkMaintenanceDesc
+	ch <- openDowntimesDesc
+}
+
+func (c *storeCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, env := range c.cfg.Envs() {
+		status, err := c.store.Status(env.Id)
+		if err != nil {
+			log.Printf("error collecting metrics for %v: %v\n", env.Id, err)
+			continue
+		}
+		for _, s := range status {
+			for _, st := range []string{StatusUp, StatusDegraded, StatusDown} {
+				value := 0.0
+				if s.Status == st {
+					value = 1
+				}
+				ch <- prometheus.MustNewConstMetric(checkStatusDesc, prometheus.GaugeValue, value, env.Id, s.Check, st)
+			}
+			maintenance := 0.0
+			if s.Maintenance {
+				maintenance = 1
+			}
+			ch <- prometheus.MustNewConstMetric(checkMaintenanceDesc, prometheus.GaugeValue, maintenance, env.Id, s.Check)
+		}
+
+		open, err := c.store.CountOpenDowntimes(env.Id)
+		if err != nil {
+			log.Printf("error collecting metrics for %v: %v\n", env.Id, err)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(openDowntimesDesc, prometheus.GaugeValue, float64(open), env.Id)
+	}
+}
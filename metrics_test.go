@@ -0,0 +1,79 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Metrics(t *testing.T) {
+	cfg := testConfig(t)
+	store, err := NewStore(cfg, &NotifyMock{})
+	require.NoError(t, err)
+	defer store.Close()
+
+	metrics := NewMetrics(cfg, store, func() int { return 3 })
+
+	expiry := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, result := range []Result{upResult("check1"), downResult("check2"), downResult("check2")} {
+		if result.Check == "check1" {
+			result.CertExpiry = expiry
+		}
+		metrics.Observe(result)
+		require.NoError(t, store.InsertResult(result))
+	}
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, 200, rec.Code)
+	body, _ := ioutil.ReadAll(rec.Body)
+
+	for _, line := range []string{
+		`insantus_check_status{check="check1",environment="testEnv",status="UP"} 1`,
+		`insantus_check_status{check="check1",environment="testEnv",status="DOWN"} 0`,
+		`insantus_check_status{check="check2",environment="testEnv",status="DOWN"} 1`,
+		`insantus_check_maintenance{check="check2",environment="testEnv"} 0`,
+		`insantus_open_downtimes{environment="testEnv"} 1`,
+		`insantus_check_executions_total{check="check2",environment="testEnv",status="DOWN"} 2`,
+		`insantus_check_duration_seconds_count{check="check1",environment="testEnv"} 1`,
+		`insantus_cert_expiry_timestamp_seconds{check="check1",environment="testEnv"} 1.893456e+09`,
+		`insantus_check_queue_length 3`,
+	} {
+		assert.Contains(t, string(body), line)
+	}
+	assert.NotContains(t, string(body), `insantus_cert_expiry_timestamp_seconds{check="check2"`)
+}
+
+func Test_Metrics_RemoveChecks(t *testing.T) {
+	cfg := testConfig(t)
+	store, err := NewStore(cfg, &NotifyMock{})
+	require.NoError(t, err)
+	defer store.Close()
+
+	metrics := NewMetrics(cfg, store, func() int { return 0 })
+	for _, result := range []Result{upResult("check1"), downResult("check2"), degradedResult("check2")} {
+		result.CertExpiry = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		metrics.Observe(result)
+	}
+
+	oldEnvs := cfg.Envs()
+	envs := []Env{{Id: "testEnv", Checks: []Check{{Id: "check1", Name: "Check 1"}}}}
+	cfg.SetEnvs(envs)
+	require.NoError(t, store.updateChecks(cfg))
+	metrics.RemoveChecks(oldEnvs)
+
+	// a result of the removed check, which was still running, is ignored
+	metrics.Observe(downResult("check2"))
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := ioutil.ReadAll(rec.Body)
+
+	assert.Contains(t, string(body), `insantus_check_duration_seconds_count{check="check1",environment="testEnv"} 1`)
+	assert.Contains(t, string(body), `insantus_cert_expiry_timestamp_seconds{check="check1",environment="testEnv"}`)
+	assert.NotContains(t, string(body), `check="check2"`)
+}
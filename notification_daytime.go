@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	defaultDayStart = "07:00"
+	defaultDayEnd   = "19:00"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// workingHours are the parsed daytime settings of a notification.
+type workingHours struct {
+	location *time.Location
+	start    int // minutes of the day
+	end      int // minutes of the day
+	workDays map[time.Weekday]bool
+}
+
+func (n Notification) daytimeSchedule() (*workingHours, error) {
+	s := &workingHours{location: time.Local}
+
+	if n.Timezone != "" {
+		var err error
+		s.location, err = time.LoadLocation(n.Timezone)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	s.start, err = parseTimeOfDay(n.DayStart, defaultDayStart)
+	if err != nil {
+		return nil, err
+	}
+	s.end, err = parseTimeOfDay(n.DayEnd, defaultDayEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(n.WorkDays) > 0 {
+		s.workDays = map[time.Weekday]bool{}
+		for _, d := range n.WorkDays {
+			weekday, exist := weekdays[strings.ToLower(d)]
+			if !exist {
+				return nil, fmt.Errorf("unknown work day %q", d)
+			}
+			s.workDays[weekday] = true
+		}
+	}
+	return s, nil
+}
+
+// isDaytime returns true, if now is within the working hours on a working day of the notification.
+// Days, which are no working days, and holidays count as nighttime.
+func (n Notification) isDaytime(now time.Time) bool {
+	s, err := n.daytimeSchedule()
+	if err != nil {
+		// the notification was validated on load
+		s, _ = Notification{}.daytimeSchedule()
+	}
+
+	now = now.In(s.location)
+	if s.workDays != nil && !s.workDays[now.Weekday()] {
+		return false
+	}
+	if n.holidays[now.Format("2006-01-02")] {
+		return false
+	}
+
+	minutes := now.Hour()*60 + now.Minute()
+	if s.start <= s.end {
+		return minutes >= s.start && minutes < s.end
+	}
+	// working hours over midnight
+	return minutes >= s.start || minutes < s.end
+}
+
+// parseTimeOfDay returns the minutes of the day of a time in the format 15:04.
+func parseTimeOfDay(value, defaultValue string) (int, error) {
+	if value == "" {
+		value = defaultValue
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected hh:mm", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// readHolidays reads a file with one date (2006-01-02) per line.
+// The date may be followed by a name, lines starting with # are ignored.
+func readHolidays(path string) (map[string]bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	holidays := map[string]bool{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		date := strings.Fields(line)[0]
+		_, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid holiday %q in %v", date, path)
+		}
+		holidays[date] = true
+	}
+	return holidays, scanner.Err()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Notification_IsDaytime(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	holidays, err := readHolidays(writeTempFile(t, "# public holidays\n2026-12-25 Christmas\n\n2026-12-26\n"))
+	require.NoError(t, err)
+
+	office := Notification{
+		DayStart: "08:30",
+		DayEnd:   "18:00",
+		WorkDays: []string{"mon", "tue", "wed", "thu", "Fri"},
+		Timezone: "Europe/Berlin",
+		holidays: holidays,
+	}
+	nightShift := Notification{DayStart: "22:00", DayEnd: "06:00", Timezone: "UTC"}
+
+	for _, test := range []struct {
+		name         string
+		notification Notification
+		now          time.Time
+		expected     bool
+	}{
+		{"default day", Notification{Timezone: "UTC"}, time.Date(2026, 3, 7, 7, 0, 0, 0, time.UTC), true},
+		{"default night", Notification{Timezone: "UTC"}, time.Date(2026, 3, 7, 19, 0, 0, 0, time.UTC), false},
+		{"before start", office, time.Date(2026, 3, 10, 8, 29, 0, 0, berlin), false},
+		{"at start", office, time.Date(2026, 3, 10, 8, 30, 0, 0, berlin), true},
+		{"other timezone", office, time.Date(2026, 3, 10, 7, 30, 0, 0, time.UTC), true},
+		{"at end", office, time.Date(2026, 3, 10, 18, 0, 0, 0, berlin), false},
+		{"weekend", office, time.Date(2026, 3, 7, 12, 0, 0, 0, berlin), false},
+		{"holiday", office, time.Date(2026, 12, 25, 12, 0, 0, 0, berlin), false},
+		{"night shift", nightShift, time.Date(2026, 3, 10, 23, 0, 0, 0, time.UTC), true},
+		{"night shift morning", nightShift, time.Date(2026, 3, 10, 5, 0, 0, 0, time.UTC), true},
+		{"night shift off", nightShift, time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC), false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.notification.isDaytime(test.now))
+		})
+	}
+}
+
+func Test_Notification_DaytimeSchedule_Invalid(t *testing.T) {
+	for _, n := range []Notification{
+		{DayStart: "8am"},
+		{DayEnd: "25:00"},
+		{WorkDays: []string{"monday"}},
+		{Timezone: "Mars/Olympus"},
+	} {
+		_, err := n.daytimeSchedule()
+		assert.Error(t, err)
+	}
+
+	_, err := readHolidays(writeTempFile(t, "25.12.2026\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var emailHtmlTemplate = template.Must(template.New("email").Parse(`<html>
+<body>
+<h3 style="color: {{if eq .Status "DOWN"}}#d9534f{{else if eq .Status "DEGRADED"}}#f0ad4e{{else}}#5cb85c{{end}}">{{.Title}}</h3>
+<table cellpadding="4">
+{{range .Downtimes}}<tr>
+<td><b>{{.Name}}</b> ({{.Check}})</td>
+{{if eq $.Status "DEGRADED"}}<td>degraded since {{.Start.Format "15:04:05 MST"}}</td>
+<td>{{.Message}}</td>{{else if eq $.Status "DOWN"}}<td>failing since {{.Start.Format "15:04:05 MST"}}</td>
+<td>{{.Message}}</td>{{else}}<td>recovered (was down for {{.End.Sub .Start}})</td>{{end}}
+</tr>
+{{end}}</table>
+{{if .DetailsUrl}}<p>See details at <a href="{{.DetailsUrl}}">{{.DetailsUrl}}</a></p>{{end}}
+</body>
+</html>
+`))
+
+// emailTimeout limits the whole smtp conversation, so that an unreachable server
+// does not block the delivery of the notifications.
+var emailTimeout = 30 * time.Second
+
+// emailConfig holds the smtp settings of an email notification,
+// taken from the params of the notification.
+type emailConfig struct {
+	host       string
+	port       int
+	user       string
+	password   string
+	from       string
+	startTLS   bool
+	skipVerify bool
+	recipients []string
+}
+
+func newEmailConfig(n Notification) (*emailConfig, error) {
+	c := &emailConfig{
+		host:     n.Params["host"],
+		port:     25,
+		user:     n.Params["user"],
+		password: n.Params["password"],
+		from:     n.Params["from"],
+	}
+	if c.host == "" {
+		return nil, errors.New("missing smtp host")
+	}
+	if c.from == "" {
+		c.from = "insantus@localhost"
+	}
+	if p, exist := n.Params["port"]; exist {
+		port, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing port")
+		}
+		c.port = port
+	}
+	if s, exist := n.Params["starttls"]; exist {
+		startTLS, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing starttls")
+		}
+		c.startTLS = startTLS
+	}
+	if s, exist := n.Params["skipVerify"]; exist {
+		skipVerify, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing skipVerify")
+		}
+		c.skipVerify = skipVerify
+	}
+
+	addresses, err := mail.ParseAddressList(n.Target)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing recipients")
+	}
+	for _, a := range addresses {
+		c.recipients = append(c.recipients, a.Address)
+	}
+	return c, nil
+}
+
+func (gw *NotificationGateway) sendEmail(n Notification, envId, title, body string, downtimes []*Downtime, status string, alert bool) error {
+	c, err := newEmailConfig(n)
+	if err != nil {
+		return errors.Wrapf(err, "email notification to %v", n.Target)
+	}
+
+	msg, err := gw.buildEmail(c, envId, title, body, downtimes, status, alert)
+	if err != nil {
+		return errors.Wrapf(err, "building email notification to %v", n.Target)
+	}
+
+	err = c.send(msg)
+	if err != nil {
+		return errors.Wrapf(err, "sending email notification to %v", n.Target)
+	}
+	return nil
+}
+
+func (gw *NotificationGateway) buildEmail(c *emailConfig, envId, title, body string, downtimes []*Downtime, status string, alert bool) ([]byte, error) {
+	detailsUrl := ""
+	if gw.cfg.SelfUrl != "" {
+		detailsUrl = fmt.Sprintf("%v/#/%v", gw.cfg.SelfUrl, envId)
+	}
+
+	htmlBody := bytes.NewBufferString("")
+	err := emailHtmlTemplate.Execute(htmlBody, map[string]interface{}{
+		"Title":      title,
+		"Status":     status,
+		"Downtimes":  downtimes,
+		"DetailsUrl": detailsUrl,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	msg := bytes.NewBufferString("")
+	parts := multipart.NewWriter(msg)
+
+	header := textproto.MIMEHeader{}
+	header.Set("From", c.from)
+	header.Set("To", strings.Join(c.recipients, ", "))
+	header.Set("Subject", mime.QEncoding.Encode("utf-8", title))
+	header.Set("Date", time.Now().Format(time.RFC1123Z))
+	header.Set("MIME-Version", "1.0")
+	header.Set("Content-Type", "multipart/alternative; boundary="+parts.Boundary())
+	if alert {
+		header.Set("X-Priority", "1 (Highest)")
+		header.Set("Importance", "high")
+	}
+	for k, values := range header {
+		for _, v := range values {
+			fmt.Fprintf(msg, "%v: %v\r\n", k, v)
+		}
+	}
+	fmt.Fprint(msg, "\r\n")
+
+	for _, p := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", body},
+		{"text/html; charset=utf-8", htmlBody.String()},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"8bit"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprint(w, p.content)
+	}
+
+	err = parts.Close()
+	return msg.Bytes(), err
+}
+
+func (c *emailConfig) send(msg []byte) error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(c.host, strconv.Itoa(c.port)), emailTimeout)
+	if err != nil {
+		return err
+	}
+	err = conn.SetDeadline(time.Now().Add(emailTimeout))
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	client, err := smtp.NewClient(conn, c.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if c.startTLS {
+		err = client.StartTLS(&tls.Config{
+			ServerName:         c.host,
+			InsecureSkipVerify: c.skipVerify,
+		})
+		if err != nil {
+			return errors.Wrap(err, "starttls")
+		}
+	}
+
+	if c.user != "" {
+		err = client.Auth(smtp.PlainAuth("", c.user, c.password, c.host))
+		if err != nil {
+			return errors.Wrap(err, "smtp auth")
+		}
+	}
+
+	err = client.Mail(c.from)
+	if err != nil {
+		return err
+	}
+	for _, r := range c.recipients {
+		err = client.Rcpt(r)
+		if err != nil {
+			return errors.Wrapf(err, "recipient %v", r)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(msg)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	return client.Quit()
+}
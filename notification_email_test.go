@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_EmailNotification_Down(t *testing.T) {
+	smtpServer := newFakeSmtpServer(t)
+	defer smtpServer.Close()
+
+	cfg := emailTestConfig(smtpServer, map[string]string{"user": "insantus", "password": "secret"})
+	gw := NewNotificationGateway(cfg)
+
+	err := gw.NotifyDown("testEnv", cfg.Environments[0].Notifications, []*Downtime{
+		{Check: "check1", Name: "Check 1", Message: "connection refused", Start: time.Now()},
+		{Check: "check2", Name: "Check 2", Message: "timeout", Start: time.Now()},
+	})
+	require.NoError(t, err)
+
+	mails := smtpServer.Mails()
+	require.Equal(t, 1, len(mails))
+	m := mails[0]
+	assert.Equal(t, "insantus@example.org", m.from)
+	assert.Equal(t, []string{"ops@example.org", "dev@example.org"}, m.to)
+	assert.Equal(t, "\x00insantus\x00secret", m.auth)
+
+	msg, err := mail.ReadMessage(strings.NewReader(m.data))
+	require.NoError(t, err)
+	assert.Equal(t, "[testEnv] 2 CHECKS WENT DOWN", msg.Header.Get("Subject"))
+	assert.Equal(t, []string{"ops@example.org, dev@example.org"}, msg.Header["To"])
+
+	plain, html := readAlternativeParts(t, msg)
+	assert.Contains(t, plain, "Check 1 (check1) is failing since")
+	assert.Contains(t, plain, "--> timeout")
+	assert.Contains(t, html, "<b>Check 2</b> (check2)")
+	assert.Contains(t, html, "connection refused")
+}
+
+func Test_EmailNotification_Recovered(t *testing.T) {
+	smtpServer := newFakeSmtpServer(t)
+	defer smtpServer.Close()
+
+	cfg := emailTestConfig(smtpServer, nil)
+	gw := NewNotificationGateway(cfg)
+
+	start := time.Now().Add(-time.Minute)
+	err := gw.NotifyRecovered("testEnv", cfg.Environments[0].Notifications, []*Downtime{
+		{Check: "check1", Name: "Check 1", Start: start, End: start.Add(time.Minute)},
+	})
+	require.NoError(t, err)
+
+	mails := smtpServer.Mails()
+	require.Equal(t, 1, len(mails))
+	assert.Equal(t, "", mails[0].auth)
+
+	msg, err := mail.ReadMessage(strings.NewReader(mails[0].data))
+	require.NoError(t, err)
+	assert.Equal(t, "[testEnv] CHECK RECOVERED: Check 1", msg.Header.Get("Subject"))
+
+	plain, html := readAlternativeParts(t, msg)
+	assert.Contains(t, plain, "Check 1 (check1) recovered (was down for 1m0s)")
+	assert.Contains(t, html, "recovered (was down for 1m0s)")
+}
+
+func Test_EmailNotification_Timeout(t *testing.T) {
+	defer func(timeout time.Duration) { emailTimeout = timeout }(emailTimeout)
+	emailTimeout = 100 * time.Millisecond
+
+	// accepts the connection, but never sends the greeting
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+
+	c := &emailConfig{
+		host:       "127.0.0.1",
+		port:       l.Addr().(*net.TCPAddr).Port,
+		from:       "insantus@example.org",
+		recipients: []string{"ops@example.org"},
+	}
+	start := time.Now()
+	assert.Error(t, c.send([]byte("test")))
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+}
+
+func Test_EmailNotification_InvalidConfig(t *testing.T) {
+	cfg := &Config{
+		Environments: []Env{
+			{
+				Id: "testEnv",
+				Notifications: []Notification{
+					{Type: "email", Target: "ops@example.org"},
+				},
+			},
+		},
+	}
+	err := NewNotificationGateway(cfg).NotifyDown("testEnv", cfg.Environments[0].Notifications, []*Downtime{{Check: "check1"}})
+	assert.Error(t, err)
+}
+
+func emailTestConfig(smtpServer *fakeSmtpServer, params map[string]string) *Config {
+	host, port, _ := net.SplitHostPort(smtpServer.Addr())
+	return &Config{
+		Environments: []Env{
+			{
+				Id: "testEnv",
+				Notifications: []Notification{
+					{
+						Type:   "email",
+						Target: "ops@example.org, Dev Team <dev@example.org>",
+						Params: mergeParams(map[string]string{
+							"host": host,
+							"port": port,
+							"from": "insantus@example.org",
+						}, params),
+					},
+				},
+			},
+		},
+	}
+}
+
+func readAlternativeParts(t *testing.T, msg *mail.Message) (plain, html string) {
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/alternative", mediaType)
+
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := r.NextPart()
+		if err != nil {
+			break
+		}
+		b, err := ioutil.ReadAll(p)
+		require.NoError(t, err)
+		if strings.HasPrefix(p.Header.Get("Content-Type"), "text/html") {
+			html = string(b)
+		} else {
+			plain = string(b)
+		}
+	}
+	return
+}
+
+type fakeMail struct {
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+// fakeSmtpServer is a minimal smtp server, which accepts all mails
+// and records them for later inspection.
+type fakeSmtpServer struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	mails    []fakeMail
+	wg       sync.WaitGroup
+}
+
+func newFakeSmtpServer(t *testing.T) *fakeSmtpServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeSmtpServer{listener: l}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			s.wg.Add(1)
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSmtpServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeSmtpServer) Mails() []fakeMail {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.mails
+}
+
+func (s *fakeSmtpServer) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *fakeSmtpServer) serve(conn net.Conn) {
+	defer s.wg.Done()
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 localhost fake smtp")
+
+	m := fakeMail{}
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch cmd {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250-localhost")
+			tp.PrintfLine("250 AUTH PLAIN")
+		case "AUTH":
+			fields := strings.Fields(line)
+			if len(fields) == 3 {
+				b, _ := base64.StdEncoding.DecodeString(fields[2])
+				m.auth = string(b)
+			}
+			tp.PrintfLine("235 ok")
+		case "MAIL":
+			m.from = strings.Trim(strings.TrimPrefix(line[4:], " FROM:"), "<>")
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			m.to = append(m.to, strings.Trim(strings.TrimPrefix(line[4:], " TO:"), "<>"))
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			b, err := ioutil.ReadAll(bufio.NewReader(tp.DotReader()))
+			if err != nil {
+				return
+			}
+			m.data = string(b)
+			s.mutex.Lock()
+			s.mails = append(s.mails, m)
+			s.mutex.Unlock()
+			m = fakeMail{}
+			tp.PrintfLine("250 ok")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("250 ok")
+		}
+	}
+}
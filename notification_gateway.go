@@ -22,7 +22,8 @@ func NewNotificationGateway(cfg *Config) *NotificationGateway {
 	}
 }
 
-func (gw *NotificationGateway) NotifyDown(envId string, downtimes []*Downtime) error {
+// NotifyDown notifies the given targets of the downtimes.
+func (gw *NotificationGateway) NotifyDown(envId string, notifications []Notification, downtimes []*Downtime) error {
 	var title string
 	if len(downtimes) == 1 {
 		title = fmt.Sprintf("[%v] CHECK DOWN: %v", envId, downtimes[0].Name)
@@ -39,10 +40,39 @@ func (gw *NotificationGateway) NotifyDown(envId string, downtimes []*Downtime) e
 		fmt.Fprintf(body, "See details at %v/#/%v\n", gw.cfg.SelfUrl, envId)
 	}
 
-	return gw.send(envId, title, body.String(), true)
+	return gw.send(envId, notifications, title, body.String(), downtimes, StatusDown)
 }
 
-func (gw *NotificationGateway) NotifyRecovered(envId string, downtimes []*Downtime) error {
+// NotifyDegraded notifies the given targets of the degraded downtimes.
+// These notifications are never sent as alert.
+func (gw *NotificationGateway) NotifyDegraded(envId string, notifications []Notification, downtimes []*Downtime) error {
+	var title string
+	if len(downtimes) == 1 {
+		title = fmt.Sprintf("[%v] CHECK DEGRADED: %v", envId, downtimes[0].Name)
+	} else {
+		title = fmt.Sprintf("[%v] %v CHECKS DEGRADED", envId, len(downtimes))
+	}
+
+	body := bytes.NewBufferString("")
+	for _, d := range downtimes {
+		fmt.Fprintf(body, "%v (%v) is degraded since %v\n--> %v\n", d.Name, d.Check, d.Start.Format("15:04:05 MST"), d.Message)
+	}
+
+	if gw.cfg.SelfUrl != "" {
+		fmt.Fprintf(body, "See details at %v/#/%v\n", gw.cfg.SelfUrl, envId)
+	}
+
+	quiet := []Notification{}
+	for _, n := range notifications {
+		n.AlertAtDaytime = false
+		n.AlertAtNighttime = false
+		quiet = append(quiet, n)
+	}
+	return gw.send(envId, quiet, title, body.String(), downtimes, StatusDegraded)
+}
+
+// NotifyRecovered notifies the given targets of the recovered downtimes.
+func (gw *NotificationGateway) NotifyRecovered(envId string, notifications []Notification, downtimes []*Downtime) error {
 	var title string
 	if len(downtimes) == 1 {
 		title = fmt.Sprintf("[%v] CHECK RECOVERED: %v", envId, downtimes[0].Name)
@@ -59,24 +89,83 @@ func (gw *NotificationGateway) NotifyRecovered(envId string, downtimes []*Downti
 		fmt.Fprintf(body, "See details at: %v/#/%v\n", gw.cfg.SelfUrl, envId)
 	}
 
-	return gw.send(envId, title, body.String(), false)
+	return gw.send(envId, notifications, title, body.String(), downtimes, StatusUp)
+}
+
+// NotifyReminder reminds the given notification targets of downtimes, which are still open.
+func (gw *NotificationGateway) NotifyReminder(envId string, notifications []Notification, downtimes []*Downtime) error {
+	var title string
+	if len(downtimes) == 1 {
+		title = fmt.Sprintf("[%v] CHECK STILL DOWN: %v", envId, downtimes[0].Name)
+	} else {
+		title = fmt.Sprintf("[%v] %v CHECKS STILL DOWN", envId, len(downtimes))
+	}
+	return gw.send(envId, notifications, title, gw.stillDownBody(envId, downtimes), downtimes, StatusDown)
+}
+
+// NotifyEscalation notifies the given notification targets of downtimes,
+// which have reached their escalation delay.
+func (gw *NotificationGateway) NotifyEscalation(envId string, notifications []Notification, downtimes []*Downtime) error {
+	var title string
+	if len(downtimes) == 1 {
+		title = fmt.Sprintf("[%v] CHECK DOWN (ESCALATED): %v", envId, downtimes[0].Name)
+	} else {
+		title = fmt.Sprintf("[%v] %v CHECKS DOWN (ESCALATED)", envId, len(downtimes))
+	}
+	return gw.send(envId, notifications, title, gw.stillDownBody(envId, downtimes), downtimes, StatusDown)
+}
+
+func (gw *NotificationGateway) stillDownBody(envId string, downtimes []*Downtime) string {
+	body := bytes.NewBufferString("")
+	for _, d := range downtimes {
+		fmt.Fprintf(body, "%v (%v) is still failing since %v (down for %v)\n--> %v\n",
+			d.Name, d.Check, d.Start.Format("15:04:05 MST"), time.Since(d.Start).Truncate(time.Second), d.Message)
+	}
+
+	if gw.cfg.SelfUrl != "" {
+		fmt.Fprintf(body, "See details at %v/#/%v\n", gw.cfg.SelfUrl, envId)
+	}
+	return body.String()
 }
 
-func (gw *NotificationGateway) send(envId, title, body string, isDown bool) error {
+// send sends the notification to all targets. The status selects the presentation:
+// DOWN and DEGRADED for failing checks, UP for recovered ones.
+func (gw *NotificationGateway) send(envId string, notifications []Notification, title, body string, downtimes []*Downtime, status string) error {
 	log.Println(title + "\n" + body)
 	notificationErrors := []string{}
 	env, _ := gw.cfg.EnvById(envId)
-	for _, n := range env.Notifications {
-		isDaytime := isDaytime()
-		alert := (n.AlertAtDaytime && isDaytime) || (n.AlertAtNighttime && !isDaytime)
+	checks := []Check{}
+	for _, d := range downtimes {
+		c, _ := env.checkById(d.Check)
+		checks = append(checks, c)
+	}
+
+	for _, n := range notifications {
+		isDaytime := n.isDaytime(time.Now())
+		alert := ((n.AlertAtDaytime && isDaytime) || (n.AlertAtNighttime && !isDaytime)) && n.alertsFor(checks)
 		switch n.Type {
 		case "hipchat":
-			err := gw.sendHipchat(n.Target, title, body, isDown, alert)
+			err := gw.sendHipchat(n.Target, title, body, status, alert)
 			if err != nil {
 				notificationErrors = append(notificationErrors, err.Error())
 			}
 		case "slack":
-			err := gw.sendSlack(n.Target, title, body, isDown, alert)
+			err := gw.sendSlack(n.Target, title, body, status, alert)
+			if err != nil {
+				notificationErrors = append(notificationErrors, err.Error())
+			}
+		case "email":
+			err := gw.sendEmail(n, envId, title, body, downtimes, status, alert)
+			if err != nil {
+				notificationErrors = append(notificationErrors, err.Error())
+			}
+		case "webhook":
+			err := gw.sendWebhook(n, envId, title, body, downtimes, status, alert)
+			if err != nil {
+				notificationErrors = append(notificationErrors, err.Error())
+			}
+		case "pagerduty":
+			err := gw.sendPagerDuty(n, envId, downtimes, status, alert)
 			if err != nil {
 				notificationErrors = append(notificationErrors, err.Error())
 			}
@@ -90,7 +179,7 @@ func (gw *NotificationGateway) send(envId, title, body string, isDown bool) erro
 	return nil
 }
 
-func (gw *NotificationGateway) sendHipchat(url, title, body string, isDown, alert bool) error {
+func (gw *NotificationGateway) sendHipchat(url, title, body string, status string, alert bool) error {
 	altertString := ""
 	if alert {
 		altertString = "@all "
@@ -100,9 +189,12 @@ func (gw *NotificationGateway) sendHipchat(url, title, body string, isDown, aler
 		"message_format": "text",
 		"notify":         true,
 	}
-	if isDown {
+	switch status {
+	case StatusDown:
 		msg["color"] = "red"
-	} else {
+	case StatusDegraded:
+		msg["color"] = "yellow"
+	default:
 		msg["color"] = "green"
 	}
 
@@ -111,25 +203,32 @@ func (gw *NotificationGateway) sendHipchat(url, title, body string, isDown, aler
 		return err
 	}
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(b))
+	client := http.Client{
+		Timeout: time.Second * 10,
+	}
+	resp, err := client.Post(url, "application/json", bytes.NewBuffer(b))
 	if err != nil {
 		return errors.Wrapf(err, "sending hipchat notification to %v", url)
 	}
+	resp.Body.Close()
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
 		return fmt.Errorf("got http status %v on sending hipchat notification to %v", resp.StatusCode, url)
 	}
 	return nil
 }
 
-func (gw *NotificationGateway) sendSlack(url, title, body string, isDown, alert bool) error {
+func (gw *NotificationGateway) sendSlack(url, title, body string, status string, alert bool) error {
 	alertString := ""
 	if alert {
 		alertString = "@Channel "
 	}
 
 	color := "good"
-	if isDown {
+	switch status {
+	case StatusDown:
 		color = "danger"
+	case StatusDegraded:
+		color = "warning"
 	}
 
 	msg := map[string]interface{}{
@@ -148,17 +247,16 @@ func (gw *NotificationGateway) sendSlack(url, title, body string, isDown, alert
 		return err
 	}
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(b))
+	client := http.Client{
+		Timeout: time.Second * 10,
+	}
+	resp, err := client.Post(url, "application/json", bytes.NewBuffer(b))
 	if err != nil {
 		return errors.Wrapf(err, "sending slack notification to %v", url)
 	}
+	resp.Body.Close()
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
 		return fmt.Errorf("got http status %v on sending slack notification to %v", resp.StatusCode, url)
 	}
 	return nil
 }
-
-func isDaytime() bool {
-	hour := time.Now().Hour()
-	return hour >= 7 && hour < 19
-}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var pagerDutyEventsUrl = "https://events.pagerduty.com/v2/enqueue"
+
+// sendPagerDuty sends one event per downtime to the PagerDuty Events API v2.
+// The target of the notification is the routing key of the PagerDuty service.
+func (gw *NotificationGateway) sendPagerDuty(n Notification, envId string, downtimes []*Downtime, status string, alert bool) error {
+	url := n.Params["url"]
+	if url == "" {
+		url = pagerDutyEventsUrl
+	}
+
+	eventErrors := []string{}
+	for _, d := range downtimes {
+		event := map[string]interface{}{
+			"routing_key": n.Target,
+			"dedup_key":   pagerDutyDedupKey(envId, d.Check),
+		}
+		if status != StatusUp {
+			event["event_action"] = "trigger"
+			event["payload"] = map[string]interface{}{
+				"summary":   fmt.Sprintf("[%v] %v: %v", envId, d.Name, d.Message),
+				"source":    "insantus",
+				"severity":  pagerDutySeverity(d.Status, alert),
+				"timestamp": d.Start.Format(time.RFC3339),
+				"component": d.Check,
+				"group":     envId,
+				"custom_details": map[string]interface{}{
+					"status":    d.Status,
+					"message":   d.Message,
+					"failCount": d.FailCount,
+				},
+			}
+			if gw.cfg.SelfUrl != "" {
+				event["links"] = []map[string]string{
+					{"href": fmt.Sprintf("%v/#/%v", gw.cfg.SelfUrl, envId), "text": "insantus"},
+				}
+			}
+		} else {
+			event["event_action"] = "resolve"
+		}
+
+		err := postPagerDutyEvent(url, event)
+		if err != nil {
+			eventErrors = append(eventErrors, err.Error())
+		}
+	}
+	if len(eventErrors) > 0 {
+		return errors.New(strings.Join(eventErrors, ", "))
+	}
+	return nil
+}
+
+func postPagerDutyEvent(url string, event map[string]interface{}) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	client := http.Client{
+		Timeout: time.Second * 10,
+	}
+	resp, err := client.Post(url, "application/json", bytes.NewBuffer(b))
+	if err != nil {
+		return errors.Wrapf(err, "sending pagerduty event %v", event["dedup_key"])
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("got http status %v on sending pagerduty event %v", resp.StatusCode, event["dedup_key"])
+	}
+	return nil
+}
+
+// pagerDutyDedupKey is stable for a check, so that a resolve event
+// closes the incident opened by the trigger event.
+func pagerDutyDedupKey(envId, checkId string) string {
+	return "insantus/" + envId + "/" + checkId
+}
+
+// pagerDutySeverity maps the check status to a PagerDuty severity.
+// Outside of the alerting times, the severity is lowered by one level.
+func pagerDutySeverity(status string, alert bool) string {
+	switch {
+	case status == StatusDegraded && alert:
+		return "warning"
+	case status == StatusDegraded:
+		return "info"
+	case alert:
+		return "critical"
+	default:
+		return "warning"
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PagerDutyNotification_TriggerAndResolve(t *testing.T) {
+	pd := newPagerDutyMock()
+	defer pd.Close()
+
+	cfg := pagerDutyTestConfig(pd.URL)
+	cfg.SelfUrl = "http://status.example.org"
+	gw := NewNotificationGateway(cfg)
+
+	downtimes := []*Downtime{
+		{Check: "check1", Name: "Check 1", Status: StatusDown, Message: "connection refused", Start: time.Now(), FailCount: 2},
+		{Check: "check2", Name: "Check 2", Status: StatusDegraded, Message: "slow", Start: time.Now(), FailCount: 3},
+	}
+	require.NoError(t, gw.NotifyDown("testEnv", cfg.Environments[0].Notifications, downtimes))
+
+	events := pd.Events()
+	require.Equal(t, 2, len(events))
+
+	e := events[0]
+	assert.Equal(t, "routing-key-42", e["routing_key"])
+	assert.Equal(t, "trigger", e["event_action"])
+	assert.Equal(t, "insantus/testEnv/check1", e["dedup_key"])
+	payload := e["payload"].(map[string]interface{})
+	assert.Equal(t, "[testEnv] Check 1: connection refused", payload["summary"])
+	assert.Equal(t, "check1", payload["component"])
+	assert.Equal(t, "testEnv", payload["group"])
+	assert.Equal(t, "warning", payload["severity"])
+	assert.Equal(t, "http://status.example.org/#/testEnv", e["links"].([]interface{})[0].(map[string]interface{})["href"])
+
+	assert.Equal(t, "insantus/testEnv/check2", events[1]["dedup_key"])
+	assert.Equal(t, "info", events[1]["payload"].(map[string]interface{})["severity"])
+
+	pd.Reset()
+	require.NoError(t, gw.NotifyRecovered("testEnv", cfg.Environments[0].Notifications, downtimes[:1]))
+
+	events = pd.Events()
+	require.Equal(t, 1, len(events))
+	assert.Equal(t, "resolve", events[0]["event_action"])
+	assert.Equal(t, "insantus/testEnv/check1", events[0]["dedup_key"])
+	assert.Nil(t, events[0]["payload"])
+}
+
+func Test_PagerDutyNotification_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	cfg := pagerDutyTestConfig(server.URL)
+	gw := NewNotificationGateway(cfg)
+	err := gw.NotifyDown("testEnv", cfg.Environments[0].Notifications, []*Downtime{{Check: "check1", Status: StatusDown}})
+	assert.Error(t, err)
+}
+
+func Test_PagerDutySeverity(t *testing.T) {
+	assert.Equal(t, "critical", pagerDutySeverity(StatusDown, true))
+	assert.Equal(t, "warning", pagerDutySeverity(StatusDown, false))
+	assert.Equal(t, "warning", pagerDutySeverity(StatusDegraded, true))
+	assert.Equal(t, "info", pagerDutySeverity(StatusDegraded, false))
+}
+
+func pagerDutyTestConfig(url string) *Config {
+	return &Config{
+		Environments: []Env{
+			{
+				Id: "testEnv",
+				Notifications: []Notification{
+					{
+						Type:   "pagerduty",
+						Target: "routing-key-42",
+						Params: map[string]string{"url": url},
+					},
+				},
+			},
+		},
+	}
+}
+
+// pagerDutyMock is a stand-in for the PagerDuty events api,
+// which records the received events.
+type pagerDutyMock struct {
+	*httptest.Server
+	mutex  sync.Mutex
+	events []map[string]interface{}
+}
+
+func newPagerDutyMock() *pagerDutyMock {
+	pd := &pagerDutyMock{}
+	pd.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := map[string]interface{}{}
+		err := json.NewDecoder(r.Body).Decode(&event)
+		if err != nil || r.URL.Path != "/" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		pd.mutex.Lock()
+		pd.events = append(pd.events, event)
+		pd.mutex.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"status":"success","message":"Event processed"}`))
+	}))
+	return pd
+}
+
+func (pd *pagerDutyMock) Events() []map[string]interface{} {
+	pd.mutex.Lock()
+	defer pd.mutex.Unlock()
+	return pd.events
+}
+
+func (pd *pagerDutyMock) Reset() {
+	pd.mutex.Lock()
+	defer pd.mutex.Unlock()
+	pd.events = nil
+}
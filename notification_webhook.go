@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var defaultWebhookTemplate = `{"environment": {{json .EnvId}}, "title": {{json .Title}}, "text": {{json .Body}}, "status": {{json .Status}}, "isDown": {{.IsDown}}, "alert": {{.Alert}}, "downtimes": {{json .Downtimes}}}`
+
+var webhookTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// WebhookData is the data, available in the body template of a webhook notification.
+type WebhookData struct {
+	EnvId     string
+	Title     string
+	Body      string
+	Status    string
+	IsDown    bool
+	Alert     bool
+	Downtimes []*Downtime
+}
+
+func (gw *NotificationGateway) sendWebhook(n Notification, envId, title, body string, downtimes []*Downtime, status string, alert bool) error {
+	method := n.Params["method"]
+	if method == "" {
+		method = "POST"
+	}
+
+	bodyTemplate := n.Params["template"]
+	if bodyTemplate == "" {
+		bodyTemplate = defaultWebhookTemplate
+	}
+	tmpl, err := template.New("webhook").Funcs(webhookTemplateFuncs).Parse(bodyTemplate)
+	if err != nil {
+		return errors.Wrapf(err, "parsing webhook template for %v", n.Target)
+	}
+
+	payload := bytes.NewBufferString("")
+	err = tmpl.Execute(payload, WebhookData{
+		EnvId:     envId,
+		Title:     title,
+		Body:      body,
+		Status:    status,
+		IsDown:    status == StatusDown,
+		Alert:     alert,
+		Downtimes: downtimes,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "rendering webhook template for %v", n.Target)
+	}
+
+	r, err := http.NewRequest(method, n.Target, payload)
+	if err != nil {
+		return errors.Wrapf(err, "creating webhook request to %v", n.Target)
+	}
+	r.Header.Set("User-Agent", "statuspage")
+	r.Header.Set("Content-Type", "application/json")
+	for k, v := range n.Params {
+		if strings.HasPrefix(k, "header-") {
+			r.Header.Set(strings.TrimPrefix(k, "header-"), v)
+		}
+	}
+
+	client := http.Client{
+		Timeout: time.Second * 10,
+	}
+	resp, err := client.Do(r)
+	if err != nil {
+		return errors.Wrapf(err, "sending webhook notification to %v", n.Target)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("got http status %v on sending webhook notification to %v", resp.StatusCode, n.Target)
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WebhookNotification_Template(t *testing.T) {
+	var method, header, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		header = r.Header.Get("X-Token")
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer server.Close()
+
+	cfg := webhookTestConfig(server.URL, map[string]string{
+		"method":         "PUT",
+		"header-X-Token": "secret",
+		"template":       `{{.EnvId}}|{{.IsDown}}|{{.Alert}}|{{range .Downtimes}}{{.Check}}:{{.Message}};{{end}}`,
+	})
+
+	err := NewNotificationGateway(cfg).NotifyDown("testEnv", cfg.Environments[0].Notifications, []*Downtime{
+		{Check: "check1", Name: "Check 1", Message: "connection refused", Start: time.Now()},
+		{Check: "check2", Name: "Check 2", Message: "timeout", Start: time.Now()},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "PUT", method)
+	assert.Equal(t, "secret", header)
+	assert.Equal(t, "testEnv|true|false|check1:connection refused;check2:timeout;", body)
+}
+
+func Test_WebhookNotification_DefaultTemplate(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+	}))
+	defer server.Close()
+
+	cfg := webhookTestConfig(server.URL, nil)
+
+	err := NewNotificationGateway(cfg).NotifyRecovered("testEnv", cfg.Environments[0].Notifications, []*Downtime{
+		{Check: "check1", Name: "Check 1", Recovered: true},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "testEnv", body["environment"])
+	assert.Equal(t, "[testEnv] CHECK RECOVERED: Check 1", body["title"])
+	assert.Equal(t, false, body["isDown"])
+	downtimes := body["downtimes"].([]interface{})
+	require.Equal(t, 1, len(downtimes))
+	assert.Equal(t, "check1", downtimes[0].(map[string]interface{})["check"])
+}
+
+func Test_WebhookNotification_Reminder(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer server.Close()
+
+	cfg := webhookTestConfig(server.URL, map[string]string{"template": "{{.Title}}|{{.IsDown}}"})
+
+	err := NewNotificationGateway(cfg).NotifyReminder("testEnv", cfg.Environments[0].Notifications, []*Downtime{
+		{Check: "check1", Name: "Check 1", Start: time.Now().Add(-time.Hour)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "[testEnv] CHECK STILL DOWN: Check 1|true", body)
+}
+
+func Test_WebhookNotification_DegradedDoesNotAlert(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer server.Close()
+
+	cfg := webhookTestConfig(server.URL, map[string]string{"template": "{{.Title}}|{{.Alert}}"})
+	cfg.Environments[0].Notifications[0].AlertAtDaytime = true
+	cfg.Environments[0].Notifications[0].AlertAtNighttime = true
+	gw := NewNotificationGateway(cfg)
+
+	err := gw.NotifyDegraded("testEnv", cfg.Environments[0].Notifications, []*Downtime{{Check: "check1", Name: "Check 1"}})
+	require.NoError(t, err)
+	assert.Equal(t, "[testEnv] CHECK DEGRADED: Check 1|false", body)
+
+	err = gw.NotifyDown("testEnv", cfg.Environments[0].Notifications, []*Downtime{{Check: "check1", Name: "Check 1"}})
+	require.NoError(t, err)
+	assert.Equal(t, "[testEnv] CHECK DOWN: Check 1|true", body)
+}
+
+func Test_WebhookNotification_Status(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer server.Close()
+
+	cfg := webhookTestConfig(server.URL, map[string]string{"template": "{{.Status}}|{{.IsDown}}"})
+	gw := NewNotificationGateway(cfg)
+	targets := cfg.Environments[0].Notifications
+	downtimes := []*Downtime{{Check: "check1", Name: "Check 1"}}
+
+	require.NoError(t, gw.NotifyDegraded("testEnv", targets, downtimes))
+	assert.Equal(t, "DEGRADED|false", body)
+
+	require.NoError(t, gw.NotifyDown("testEnv", targets, downtimes))
+	assert.Equal(t, "DOWN|true", body)
+
+	require.NoError(t, gw.NotifyRecovered("testEnv", targets, downtimes))
+	assert.Equal(t, "UP|false", body)
+}
+
+func Test_WebhookNotification_AlertSeverities(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer server.Close()
+
+	cfg := webhookTestConfig(server.URL, map[string]string{"template": "{{.Alert}}"})
+	cfg.Environments[0].Checks = []Check{
+		{Id: "checkout", Severity: "critical"},
+		{Id: "search"},
+	}
+	n := &cfg.Environments[0].Notifications[0]
+	n.AlertAtDaytime = true
+	n.AlertAtNighttime = true
+	n.AlertSeverities = []string{"critical"}
+	gw := NewNotificationGateway(cfg)
+
+	require.NoError(t, gw.NotifyDown("testEnv", cfg.Environments[0].Notifications, []*Downtime{{Check: "search"}}))
+	assert.Equal(t, "false", body)
+
+	require.NoError(t, gw.NotifyDown("testEnv", cfg.Environments[0].Notifications, []*Downtime{{Check: "search"}, {Check: "checkout"}}))
+	assert.Equal(t, "true", body)
+}
+
+func Test_WebhookNotification_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	for _, test := range []struct {
+		name   string
+		params map[string]string
+	}{
+		{"http error", nil},
+		{"invalid template", map[string]string{"template": "{{.Foo"}},
+		{"unknown field", map[string]string{"template": "{{.Foo}}"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := webhookTestConfig(server.URL, test.params)
+			err := NewNotificationGateway(cfg).NotifyDown("testEnv", cfg.Environments[0].Notifications, []*Downtime{{Check: "check1"}})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func webhookTestConfig(url string, params map[string]string) *Config {
+	return &Config{
+		Environments: []Env{
+			{
+				Id: "testEnv",
+				Notifications: []Notification{
+					{
+						Type:   "webhook",
+						Target: url,
+						Params: mergeParams(map[string]string{}, params),
+					},
+				},
+			},
+		},
+	}
+}
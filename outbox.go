@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	maxDeliveryAttempts = 8
+	deliveryBackoff     = time.Minute
+	maxDeliveryBackoff  = time.Hour
+	// deliveryLease is the time, a delivery is claimed by a sender.
+	// A delivery, which is still sending afterwards, is retried, e.g. after a crash.
+	deliveryLease = 5 * time.Minute
+)
+
+// deliverInBackground leaves the sending of the enqueued notifications to runDeliveries,
+// so that a slow target does not block the processing of the results.
+func (store *Store) deliverInBackground() {
+	store.deliveryWakeup = make(chan bool, 1)
+}
+
+// enqueueNotifications stores a delivery for each of the targets, the downtimes are routed to.
+// In the background, the deliveries are sent by runDeliveries, otherwise they are tried right away.
+// A failed delivery is retried later, so only errors of the database are returned.
+func (store *Store) enqueueNotifications(environment, kind string, notifications []Notification, downtimes []*Downtime, now time.Time) error {
+	now = now.UTC()
+	env, _ := store.cfg.EnvById(environment)
+	for _, n := range notifications {
+		// only the downtimes of the checks, which are routed to the target
+		routed := []*Downtime{}
+		ids := []string{}
+		for _, d := range downtimes {
+			c, _ := env.checkById(d.Check)
+			if n.routes(c) {
+				routed = append(routed, d)
+				ids = append(ids, strconv.Itoa(int(d.Id)))
+			}
+		}
+		if len(routed) == 0 {
+			continue
+		}
+
+		delivery := &NotificationDelivery{
+			Environment: environment,
+			Kind:        kind,
+			Type:        n.Type,
+			Target:      n.Target,
+			DowntimeIds: strings.Join(ids, ","),
+			Status:      DeliveryPending,
+			Created:     now,
+			NextAttempt: now,
+		}
+		if store.deliveryWakeup == nil {
+			// created as claimed, so that the timer does not send it at the same time
+			delivery.Status = DeliverySending
+			delivery.NextAttempt = now.Add(deliveryLease)
+		}
+		err := store.db.Create(delivery).Error
+		if err != nil {
+			return errors.Wrap(err, "create notification delivery")
+		}
+
+		if store.deliveryWakeup == nil {
+			err = store.deliver(delivery, n, routed, now)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if store.deliveryWakeup != nil {
+		select {
+		case store.deliveryWakeup <- true:
+		default:
+			// already woken up
+		}
+	}
+	return nil
+}
+
+// deliverNotifications retries the pending deliveries, which are due at now,
+// and the ones, which are still sending after their lease.
+func (store *Store) deliverNotifications(now time.Time) error {
+	now = now.UTC()
+	pending := []*NotificationDelivery{}
+	err := store.db.
+		Where(`status IN (?) AND next_attempt <= ?`, []string{DeliveryPending, DeliverySending}, now).
+		Order("id").
+		Find(&pending).
+		Error
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range pending {
+		claimed, err := store.claimDelivery(delivery, now)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		n, downtimes, err := store.deliveryContent(delivery)
+		if err == nil && delivery.Kind != NotificationRecovered {
+			// a late down notification would be misleading for recovered downtimes
+			downtimes = openDowntimes(downtimes)
+			if len(downtimes) == 0 {
+				err = errors.New("downtimes recovered before delivery")
+			}
+		}
+		if err != nil {
+			delivery.Status = DeliveryDead
+			delivery.LastError = err.Error()
+			err = store.db.Save(delivery).Error
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		err = store.deliver(delivery, n, downtimes, now)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// claimDelivery marks the delivery as sending, if no one else has claimed it in the meantime.
+func (store *Store) claimDelivery(delivery *NotificationDelivery, now time.Time) (bool, error) {
+	db := store.db.
+		Model(&NotificationDelivery{}).
+		Where(`id = ? AND status IN (?) AND next_attempt <= ?`, delivery.Id, []string{DeliveryPending, DeliverySending}, now).
+		UpdateColumns(map[string]interface{}{
+			"status":       DeliverySending,
+			"next_attempt": now.Add(deliveryLease),
+		})
+	if db.Error != nil {
+		return false, errors.Wrap(db.Error, "claim notification delivery")
+	}
+	delivery.Status = DeliverySending
+	delivery.NextAttempt = now.Add(deliveryLease)
+	return db.RowsAffected == 1, nil
+}
+
+func openDowntimes(downtimes []*Downtime) []*Downtime {
+	open := []*Downtime{}
+	for _, d := range downtimes {
+		if !d.Recovered {
+			open = append(open, d)
+		}
+	}
+	return open
+}
+
+// deliveryContent loads the current configuration of the target and the downtimes of the delivery.
+func (store *Store) deliveryContent(delivery *NotificationDelivery) (Notification, []*Downtime, error) {
+	env, _ := store.cfg.EnvById(delivery.Environment)
+	var notification *Notification
+	for i, n := range env.Notifications {
+		if n.Type == delivery.Type && n.Target == delivery.Target {
+			notification = &env.Notifications[i]
+			break
+		}
+	}
+	if notification == nil {
+		return Notification{}, nil, errors.New("notification target removed from the configuration")
+	}
+
+	downtimes := []*Downtime{}
+	err := store.db.
+		Where(`id IN (?)`, strings.Split(delivery.DowntimeIds, ",")).
+		Find(&downtimes).
+		Error
+	if err != nil {
+		return Notification{}, nil, errors.Wrap(err, "query downtimes")
+	}
+	if len(downtimes) == 0 {
+		return Notification{}, nil, errors.New("downtimes not found")
+	}
+	return *notification, downtimes, nil
+}
+
+// deliver sends the notification to the target and updates the state of the delivery.
+// On failure, the next attempt is scheduled with an exponential backoff,
+// until the delivery is given up after maxDeliveryAttempts.
+func (store *Store) deliver(delivery *NotificationDelivery, n Notification, downtimes []*Downtime, now time.Time) error {
+	notifications := []Notification{n}
+	var err error
+	switch delivery.Kind {
+	case NotificationDown:
+		err = store.notifyer.NotifyDown(delivery.Environment, notifications, downtimes)
+	case NotificationDegraded:
+		err = store.notifyer.NotifyDegraded(delivery.Environment, notifications, downtimes)
+	case NotificationRecovered:
+		err = store.notifyer.NotifyRecovered(delivery.Environment, notifications, downtimes)
+	case NotificationReminder:
+		err = store.notifyer.NotifyReminder(delivery.Environment, notifications, downtimes)
+	case NotificationEscalation:
+		err = store.notifyer.NotifyEscalation(delivery.Environment, notifications, downtimes)
+	default:
+		err = fmt.Errorf("unknown notification kind %v", delivery.Kind)
+	}
+
+	delivery.Attempts++
+	if err == nil {
+		delivery.Status = DeliverySent
+		delivery.Sent = now
+		delivery.LastError = ""
+	} else {
+		log.Printf("error delivering %v notification for %v to %v (attempt %v): %v\n",
+			delivery.Kind, delivery.Environment, delivery.Type, delivery.Attempts, err)
+		delivery.LastError = err.Error()
+		if delivery.Attempts >= maxDeliveryAttempts {
+			delivery.Status = DeliveryDead
+		} else {
+			delivery.Status = DeliveryPending
+			delivery.NextAttempt = now.Add(deliveryBackoffAfter(delivery.Attempts))
+		}
+	}
+
+	err = store.db.Save(delivery).Error
+	if err != nil {
+		return errors.Wrap(err, "save notification delivery")
+	}
+	return nil
+}
+
+func deliveryBackoffAfter(attempts int) time.Duration {
+	backoff := deliveryBackoff
+	for i := 1; i < attempts && backoff < maxDeliveryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxDeliveryBackoff {
+		backoff = maxDeliveryBackoff
+	}
+	return backoff
+}
+
+// DeliveryFailures returns the newest deliveries of the environment,
+// which have failed and are retried or given up.
+func (store *Store) DeliveryFailures(environment string, limit int) (deliveries []*NotificationDelivery, err error) {
+	err = store.db.
+		Where(`environment = ? AND (status = ? OR (status = ? AND attempts > 0))`, environment, DeliveryDead, DeliveryPending).
+		Order("id DESC").
+		Limit(limit).
+		Find(&deliveries).
+		Error
+	return
+}
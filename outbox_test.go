@@ -0,0 +1,174 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Outbox_NoDoubleDelivery(t *testing.T) {
+	cfg := testConfig(t)
+	notifyMock := &NotifyMock{}
+	store, err := NewStore(cfg, notifyMock)
+	require.NoError(t, err)
+	defer store.Close()
+
+	// the timer runs, while the notification is sent
+	notifyMock.onNotify = func() {
+		require.NoError(t, store.deliverNotifications(time.Now().Add(time.Second)))
+	}
+	require.NoError(t, store.InsertResult(downResult("check1")))
+	require.NoError(t, store.InsertResult(downResult("check1")))
+	assert.Equal(t, []string{"mock"}, notifyMock.downTypes)
+
+	// a delivery, which is still sending after its lease, is retried
+	notifyMock.reset()
+	delivery := &NotificationDelivery{}
+	require.NoError(t, store.db.First(delivery).Error)
+	assert.Equal(t, DeliverySent, delivery.Status)
+	require.NoError(t, store.db.Model(delivery).UpdateColumns(map[string]interface{}{
+		"status":       DeliverySending,
+		"next_attempt": time.Now().UTC().Add(deliveryLease),
+	}).Error)
+
+	require.NoError(t, store.deliverNotifications(time.Now().Add(time.Minute)))
+	notifyMock.AssertNoNotifications(t)
+	require.NoError(t, store.deliverNotifications(time.Now().Add(deliveryLease+time.Second)))
+	assert.Equal(t, []string{"mock"}, notifyMock.downTypes)
+}
+
+func Test_Outbox_RetryFailedTarget(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Environments[0].Notifications = []Notification{
+		{Type: "slack", Target: "https://slack.example.org"},
+		{Type: "webhook", Target: "https://hooks.example.org"},
+	}
+	notifyMock := &NotifyMock{failingType: "slack"}
+	store, err := NewStore(cfg, notifyMock)
+	require.NoError(t, err)
+	defer store.Close()
+
+	// the broken target does not block the other one
+	require.NoError(t, store.InsertResult(downResult("check1")))
+	require.NoError(t, store.InsertResult(downResult("check1")))
+	require.Equal(t, 1, len(notifyMock.downs))
+	notifyMock.reset()
+
+	// further results do not repeat the notification
+	require.NoError(t, store.InsertResult(downResult("check1")))
+	notifyMock.AssertNoNotifications(t)
+
+	failures, err := store.DeliveryFailures("testEnv", 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(failures))
+	assert.Equal(t, "slack", failures[0].Type)
+	assert.Equal(t, NotificationDown, failures[0].Kind)
+	assert.Equal(t, DeliveryPending, failures[0].Status)
+	assert.Equal(t, 1, failures[0].Attempts)
+	assert.Equal(t, "sending slack failed", failures[0].LastError)
+
+	// not retried before the backoff
+	now := time.Now()
+	require.NoError(t, store.deliverNotifications(now.Add(30*time.Second)))
+	failures, err = store.DeliveryFailures("testEnv", 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, failures[0].Attempts)
+
+	require.NoError(t, store.deliverNotifications(now.Add(61*time.Second)))
+	failures, err = store.DeliveryFailures("testEnv", 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, failures[0].Attempts)
+	assert.True(t, failures[0].NextAttempt.After(now.Add(3*time.Minute)))
+
+	notifyMock.failingType = ""
+	require.NoError(t, store.deliverNotifications(now.Add(4*time.Minute)))
+	require.Equal(t, 1, len(notifyMock.downs))
+	assert.Equal(t, "check1", notifyMock.downs[0].Check)
+
+	failures, err = store.DeliveryFailures("testEnv", 10)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(failures))
+}
+
+func Test_Outbox_DeadLetter(t *testing.T) {
+	cfg := testConfig(t)
+	notifyMock := &NotifyMock{failingType: "mock"}
+	store, err := NewStore(cfg, notifyMock)
+	require.NoError(t, err)
+	defer store.Close()
+
+	require.NoError(t, store.InsertResult(downResult("check1")))
+	require.NoError(t, store.InsertResult(downResult("check1")))
+
+	now := time.Now()
+	for i := 0; i < maxDeliveryAttempts+2; i++ {
+		require.NoError(t, store.deliverNotifications(now.Add(time.Duration(i)*maxDeliveryBackoff)))
+	}
+
+	failures, err := store.DeliveryFailures("testEnv", 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(failures))
+	assert.Equal(t, DeliveryDead, failures[0].Status)
+	assert.Equal(t, maxDeliveryAttempts, failures[0].Attempts)
+}
+
+func Test_Outbox_RecoveredBeforeDelivery(t *testing.T) {
+	cfg := testConfig(t)
+	notifyMock := &NotifyMock{failingType: "mock"}
+	store, err := NewStore(cfg, notifyMock)
+	require.NoError(t, err)
+	defer store.Close()
+
+	require.NoError(t, store.InsertResult(downResult("check1")))
+	require.NoError(t, store.InsertResult(downResult("check1")))
+	notifyMock.failingType = ""
+	require.NoError(t, store.InsertResult(upResult("check1")))
+	require.Equal(t, 1, len(notifyMock.ups))
+	notifyMock.reset()
+
+	// the late down notification is given up
+	require.NoError(t, store.deliverNotifications(time.Now().Add(time.Hour)))
+	notifyMock.AssertNoNotifications(t)
+
+	failures, err := store.DeliveryFailures("testEnv", 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(failures))
+	assert.Equal(t, DeliveryDead, failures[0].Status)
+	assert.Equal(t, "downtimes recovered before delivery", failures[0].LastError)
+}
+
+func Test_DeliveryBackoff(t *testing.T) {
+	assert.Equal(t, time.Minute, deliveryBackoffAfter(1))
+	assert.Equal(t, 2*time.Minute, deliveryBackoffAfter(2))
+	assert.Equal(t, 8*time.Minute, deliveryBackoffAfter(4))
+	assert.Equal(t, time.Hour, deliveryBackoffAfter(20))
+}
+
+func Test_Outbox_DeliverInBackground(t *testing.T) {
+	cfg := testConfig(t)
+	notifyMock := &NotifyMock{}
+	store, err := NewStore(cfg, notifyMock)
+	require.NoError(t, err)
+	defer store.Close()
+	store.deliverInBackground()
+
+	// the results only enqueue the notification
+	require.NoError(t, store.InsertResult(downResult("check1")))
+	require.NoError(t, store.InsertResult(downResult("check1")))
+	notifyMock.AssertNoNotifications(t)
+
+	delivery := &NotificationDelivery{}
+	require.NoError(t, store.db.First(delivery).Error)
+	assert.Equal(t, DeliveryPending, delivery.Status)
+
+	// and wake up the background delivery
+	select {
+	case <-store.deliveryWakeup:
+	default:
+		t.Fatal("delivery not woken up")
+	}
+	require.NoError(t, store.deliverNotifications(time.Now()))
+	assert.Equal(t, []string{"mock"}, notifyMock.downTypes)
+}
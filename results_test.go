@@ -0,0 +1,102 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Store_Results(t *testing.T) {
+	store, err := NewStore(testConfig(t), &NotifyMock{})
+	require.NoError(t, err)
+	defer store.Close()
+
+	start := time.Now().Add(-time.Hour)
+	ids := []uint{}
+	for i, result := range []Result{
+		upResult("check1"), downResult("check1"), degradedResult("check1"), downResult("check1"), upResult("check1"),
+	} {
+		result.Timestamp = start.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, store.InsertResult(result))
+		ids = append(ids, result.Id)
+	}
+	require.NoError(t, store.InsertResult(downResult("check2")))
+
+	all := ResultQuery{From: start, To: time.Now(), Limit: 10}
+	results, err := store.Results("testEnv", "check1", all)
+	require.NoError(t, err)
+	require.Equal(t, 5, len(results))
+	assert.Equal(t, ids[4], results[0].Id)
+	assert.Equal(t, "", results[1].Detail)
+
+	q := all
+	q.IncludeDetail = true
+	q.Statuses = []string{StatusDown, StatusDegraded}
+	results, err = store.Results("testEnv", "check1", q)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(results))
+	assert.Equal(t, "some error detail", results[0].Detail)
+
+	// the second page after the cursor
+	q.Limit = 2
+	q.Before = results[1].Id
+	results, err = store.Results("testEnv", "check1", q)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(results))
+	assert.Equal(t, ids[1], results[0].Id)
+
+	q = all
+	q.From = start.Add(90 * time.Second)
+	q.To = start.Add(210 * time.Second)
+	results, err = store.Results("testEnv", "check1", q)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{ids[3], ids[2]}, []uint{results[0].Id, results[1].Id})
+}
+
+func Test_Store_Results_LocalTimezone(t *testing.T) {
+	defer localTimezone(time.FixedZone("JST", 9*60*60))()
+
+	store, err := NewStore(testConfig(t), &NotifyMock{})
+	require.NoError(t, err)
+	defer store.Close()
+
+	result := upResult("check1")
+	result.Timestamp = time.Now().Add(-10 * time.Minute)
+	require.NoError(t, store.InsertResult(result))
+
+	// the range of the api is given in UTC
+	to := time.Now().UTC()
+	results, err := store.Results("testEnv", "check1", ResultQuery{From: to.Add(-time.Hour), To: to, Limit: 10})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(results))
+	assert.True(t, result.Timestamp.Equal(results[0].Timestamp))
+}
+
+func Test_Store_Downtimes_Paging(t *testing.T) {
+	store, err := NewStore(testConfig(t), &NotifyMock{})
+	require.NoError(t, err)
+	defer store.Close()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, store.InsertResult(downResult("check1")))
+		require.NoError(t, store.InsertResult(upResult("check1")))
+	}
+	require.NoError(t, store.InsertResult(downResult("check2")))
+
+	page, err := store.Downtimes("testEnv", 0, 4)
+	require.NoError(t, err)
+	require.Equal(t, 4, len(page))
+	// the open downtime first
+	assert.Equal(t, "check2", page[0].Check)
+
+	rest, err := store.Downtimes("testEnv", 4, 4)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(rest))
+	for _, d := range page {
+		for _, r := range rest {
+			assert.NotEqual(t, d.Id, r.Id)
+		}
+	}
+}
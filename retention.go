@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// pruneBatchSize limits the rows deleted by one statement,
+// so that the inserts of the results are not blocked for long.
+var pruneBatchSize = 1000
+
+// enableIncrementalVacuum switches the database to incremental auto vacuum,
+// so that the space of the pruned rows can be freed without rewriting the whole file.
+// Existing databases are converted by a full VACUUM once.
+func (store *Store) enableIncrementalVacuum() error {
+	var mode int
+	err := store.db.DB().QueryRow(`PRAGMA auto_vacuum`).Scan(&mode)
+	if err != nil {
+		return errors.Wrap(err, "query auto_vacuum")
+	}
+	if mode == 2 {
+		return nil
+	}
+
+	var tables int
+	err = store.db.DB().QueryRow(`SELECT count(*) FROM sqlite_master`).Scan(&tables)
+	if err != nil {
+		return errors.Wrap(err, "query tables")
+	}
+	if tables > 0 {
+		log.Println("converting the database to incremental vacuum")
+	}
+	// the mode is applied by the VACUUM on the same connection
+	conn, err := store.db.DB().Conn(context.Background())
+	if err != nil {
+		return errors.Wrap(err, "open connection")
+	}
+	defer conn.Close()
+	_, err = conn.ExecContext(context.Background(), `PRAGMA auto_vacuum = INCREMENTAL`)
+	if err != nil {
+		return errors.Wrap(err, "set auto_vacuum")
+	}
+	_, err = conn.ExecContext(context.Background(), `VACUUM`)
+	return errors.Wrap(err, "vacuum")
+}
+
+// pruned counts the rows deleted by prune.
+type pruned struct {
+	results       int64
+	downtimes     int64
+	notifications int64
+	rollups       int64
+}
+
+func (p pruned) total() int64 {
+	return p.results + p.downtimes + p.notifications + p.rollups
+}
+
+// prune deletes the rows, which are older than their configured retention,
+// and frees the space afterwards. The results referenced by the status of the checks
+// and by open downtimes are kept, as well as the open downtimes and the notifications not yet delivered.
+// The minute rollups are kept as long as the results, the hour and day rollups by their own retention.
+// The status changes are deleted with their downtimes.
+func (store *Store) prune(now time.Time) (p pruned, err error) {
+	now = now.UTC()
+	if store.cfg.RetainResults > 0 {
+		p.results, err = store.deleteInBatches(
+			`DELETE FROM result WHERE id IN (
+				SELECT id FROM result
+				WHERE timestamp < ?
+				AND id NOT IN (SELECT last_result_id FROM check_status)
+				AND id NOT IN (SELECT last_result_id FROM downtime WHERE recovered = 0)
+				LIMIT ?)`,
+			now.Add(-store.cfg.RetainResults))
+		if err != nil {
+			return p, errors.Wrap(err, "prune results")
+		}
+
+		p.rollups, err = store.deleteRollups(RollupMinute, now.Add(-store.cfg.RetainResults))
+		if err != nil {
+			return p, errors.Wrap(err, "prune rollups")
+		}
+	}
+
+	if store.cfg.RetainRollups > 0 {
+		for _, resolution := range []string{RollupHour, RollupDay} {
+			deleted, err := store.deleteRollups(resolution, now.Add(-store.cfg.RetainRollups))
+			p.rollups += deleted
+			if err != nil {
+				return p, errors.Wrap(err, "prune rollups")
+			}
+		}
+	}
+
+	if store.cfg.RetainDowntimes > 0 {
+		p.downtimes, err = store.deleteInBatches(
+			`DELETE FROM downtime WHERE id IN (
+				SELECT id FROM downtime
+				WHERE recovered = 1 AND "end" < ?
+				LIMIT ?)`,
+			now.Add(-store.cfg.RetainDowntimes))
+		if err != nil {
+			return p, errors.Wrap(err, "prune downtimes")
+		}
+
+		_, err = store.deleteInBatches(
+			`DELETE FROM status_change WHERE id IN (
+				SELECT id FROM status_change
+				WHERE downtime_id NOT IN (SELECT id FROM downtime)
+				LIMIT ?)`)
+		if err != nil {
+			return p, errors.Wrap(err, "prune status changes")
+		}
+	}
+
+	if store.cfg.RetainNotifications > 0 {
+		p.notifications, err = store.deleteInBatches(
+			`DELETE FROM notification_delivery WHERE id IN (
+				SELECT id FROM notification_delivery
+				WHERE status NOT IN (?, ?) AND created < ?
+				LIMIT ?)`,
+			DeliveryPending, DeliverySending, now.Add(-store.cfg.RetainNotifications))
+		if err != nil {
+			return p, errors.Wrap(err, "prune notifications")
+		}
+	}
+
+	if p.total() > 0 {
+		err = store.db.Exec(`PRAGMA incremental_vacuum`).Error
+		if err != nil {
+			return p, errors.Wrap(err, "incremental vacuum")
+		}
+	}
+	return p, nil
+}
+
+// deleteRollups deletes the rollups of the resolution, which start before t.
+func (store *Store) deleteRollups(resolution string, t time.Time) (int64, error) {
+	return store.deleteInBatches(
+		`DELETE FROM result_rollup WHERE rowid IN (
+			SELECT rowid FROM result_rollup
+			WHERE resolution = ? AND start < ?
+			LIMIT ?)`,
+		resolution, t.UTC())
+}
+
+// deleteInBatches executes the delete statement until no more rows are affected.
+// The last parameter of the statement has to be the LIMIT of the batch.
+func (store *Store) deleteInBatches(sql string, values ...interface{}) (int64, error) {
+	values = append(values, pruneBatchSize)
+	var deleted int64
+	for {
+		db := store.db.Exec(sql, values...)
+		if db.Error != nil {
+			return deleted, db.Error
+		}
+		deleted += db.RowsAffected
+		if db.RowsAffected < int64(pruneBatchSize) {
+			return deleted, nil
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Store_Prune(t *testing.T) {
+	defer func(size int) { pruneBatchSize = size }(pruneBatchSize)
+	pruneBatchSize = 2
+
+	cfg := testConfig(t)
+	cfg.RetainResults = 24 * time.Hour
+	cfg.RetainDowntimes = 24 * time.Hour
+	cfg.RetainNotifications = 24 * time.Hour
+	store, err := NewStore(cfg, &NotifyMock{})
+	require.NoError(t, err)
+	defer store.Close()
+
+	// check1 recovers, check2 stays down
+	var firstDown Result
+	for i, result := range []Result{
+		upResult("check1"), downResult("check1"), downResult("check1"), upResult("check1"),
+		downResult("check2"), downResult("check2"), downResult("check2"),
+	} {
+		if i == 4 {
+			firstDown = result
+		}
+		require.NoError(t, store.InsertResult(result))
+	}
+	// the open downtime references an older result than the status
+	require.NoError(t, store.db.Model(&Downtime{}).
+		Where(`"check" = ? AND recovered = 0`, "check2").
+		Update("last_result_id", firstDown.Id).Error)
+
+	require.NoError(t, store.db.Create(&NotificationDelivery{Environment: "testEnv", Status: DeliverySent, Created: time.Now()}).Error)
+	require.NoError(t, store.db.Create(&NotificationDelivery{Environment: "testEnv", Status: DeliveryPending, Created: time.Now()}).Error)
+
+	// nothing exceeds the retention yet
+	p, err := store.prune(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), p.total())
+
+	p, err = store.prune(time.Now().Add(48 * time.Hour))
+	require.NoError(t, err)
+	// kept: the results of the status and the one of the open downtime
+	assert.Equal(t, int64(4), p.results)
+	assert.Equal(t, int64(1), p.downtimes)
+	// only the status change of the open downtime is left
+	var changes int
+	require.NoError(t, store.db.Model(&StatusChange{}).Count(&changes).Error)
+	assert.Equal(t, 1, changes)
+	// the down and recovered notifications of the checks, and the sent one above
+	assert.Equal(t, int64(4), p.notifications)
+
+	status, err := store.Status("testEnv")
+	require.NoError(t, err)
+	for _, s := range status {
+		_, found, err := store.Result(int(s.LastResultId))
+		require.NoError(t, err)
+		assert.True(t, found, s.Check)
+	}
+	_, found, err := store.Result(int(firstDown.Id))
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	open, err := store.CountOpenDowntimes("testEnv")
+	require.NoError(t, err)
+	assert.Equal(t, 1, open)
+
+	var pending int
+	require.NoError(t, store.db.Model(&NotificationDelivery{}).Count(&pending).Error)
+	assert.Equal(t, 1, pending)
+
+	// the hour and day rollups are kept without their own retention
+	assert.True(t, p.rollups > 0)
+	var minuteRollups, otherRollups int
+	require.NoError(t, store.db.Model(&ResultRollup{}).Where(`resolution = ?`, RollupMinute).Count(&minuteRollups).Error)
+	require.NoError(t, store.db.Model(&ResultRollup{}).Where(`resolution != ?`, RollupMinute).Count(&otherRollups).Error)
+	assert.Equal(t, 0, minuteRollups)
+	assert.True(t, otherRollups >= 4)
+}
+
+func Test_Store_IncrementalVacuum(t *testing.T) {
+	// without a retention, the database is left as it is
+	cfg := testConfig(t)
+	store, err := NewStore(cfg, &NotifyMock{})
+	require.NoError(t, err)
+
+	var mode int
+	require.NoError(t, store.db.DB().QueryRow(`PRAGMA auto_vacuum`).Scan(&mode))
+	assert.Equal(t, 0, mode)
+	store.Close()
+
+	cfg.RetainResults = 24 * time.Hour
+	store, err = NewStore(cfg, &NotifyMock{})
+	require.NoError(t, err)
+	defer store.Close()
+
+	require.NoError(t, store.db.DB().QueryRow(`PRAGMA auto_vacuum`).Scan(&mode))
+	assert.Equal(t, 2, mode)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// RetryChecker re-executes a failing check, before the failure is reported.
+type RetryChecker struct {
+	checker    Checker
+	retries    int
+	retryDelay time.Duration
+}
+
+func NewRetryChecker(checker Checker, retries int, retryDelay time.Duration) *RetryChecker {
+	return &RetryChecker{
+		checker:    checker,
+		retries:    retries,
+		retryDelay: retryDelay,
+	}
+}
+
+// Check executes the checker until it succeeds or all retries are used.
+// If more than one attempt was needed, the attempts with their errors are
+// appended to the detail of the results, unless the detail is json.
+func (c *RetryChecker) Check(ctx context.Context) []Result {
+	attemptErrors := []string{}
+	for attempt := 1; ; attempt++ {
+		results := c.checker.Check(ctx)
+		failure := firstFailure(results)
+		if failure != nil {
+			attemptErrors = append(attemptErrors, failure.Message)
+		}
+
+		retry := failure != nil && attempt <= c.retries
+		if retry {
+			select {
+			case <-time.After(c.retryDelay):
+			case <-ctx.Done():
+				retry = false
+			}
+		}
+
+		if !retry {
+			if attempt > 1 {
+				addAttemptsToDetail(results, attempt, attemptErrors)
+			}
+			return results
+		}
+	}
+}
+
+func firstFailure(results []Result) *Result {
+	for i := range results {
+		if results[i].Status != StatusUp {
+			return &results[i]
+		}
+	}
+	return nil
+}
+
+func addAttemptsToDetail(results []Result, attempts int, attemptErrors []string) {
+	b := bytes.NewBufferString("")
+	fmt.Fprintf(b, "attempts: %v\n", attempts)
+	for i, e := range attemptErrors {
+		fmt.Fprintf(b, "attempt %v: %v\n", i+1, e)
+	}
+	for i := range results {
+		// json details, e.g. of spring-health checks, are shown structured on the status page
+		if isJsonDetail(results[i].Detail) {
+			continue
+		}
+		if results[i].Detail != "" {
+			results[i].Detail += "\n\n"
+		}
+		results[i].Detail += b.String()
+	}
+}
+
+func isJsonDetail(detail string) bool {
+	jsonDetails := map[string]interface{}{}
+	return json.Unmarshal([]byte(detail), &jsonDetails) == nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RetryChecker(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		statuses []string
+		retries  int
+
+		expectedStatus   string
+		expectedAttempts int
+		expectedDetail   string
+	}{
+		{
+			name:             "up",
+			statuses:         []string{StatusUp},
+			retries:          2,
+			expectedStatus:   StatusUp,
+			expectedAttempts: 1,
+		},
+		{
+			name:             "up after retry",
+			statuses:         []string{StatusDown, StatusUp},
+			retries:          2,
+			expectedStatus:   StatusUp,
+			expectedAttempts: 2,
+			expectedDetail:   "attempts: 2\nattempt 1: error 1\n",
+		},
+		{
+			name:             "down after all retries",
+			statuses:         []string{StatusDown, StatusDown, StatusDown, StatusUp},
+			retries:          2,
+			expectedStatus:   StatusDown,
+			expectedAttempts: 3,
+			expectedDetail:   "detail 3\n\nattempts: 3\nattempt 1: error 1\nattempt 2: error 2\nattempt 3: error 3\n",
+		},
+		{
+			name:             "no retries",
+			statuses:         []string{StatusDown, StatusUp},
+			retries:          0,
+			expectedStatus:   StatusDown,
+			expectedAttempts: 1,
+			expectedDetail:   "detail 1",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			checker := &sequenceChecker{statuses: test.statuses}
+			results := NewRetryChecker(checker, test.retries, time.Millisecond).Check(context.Background())
+
+			require.Equal(t, 1, len(results))
+			assert.Equal(t, test.expectedStatus, results[0].Status)
+			assert.Equal(t, test.expectedAttempts, checker.calls)
+			assert.Equal(t, test.expectedDetail, results[0].Detail)
+		})
+	}
+}
+
+func Test_RetryChecker_KeepsJsonDetail(t *testing.T) {
+	checker := &sequenceChecker{statuses: []string{StatusDown, StatusDown}, detail: `{"status":"DOWN"}`}
+	results := NewRetryChecker(checker, 1, time.Millisecond).Check(context.Background())
+
+	require.Equal(t, 1, len(results))
+	assert.Equal(t, 2, checker.calls)
+	assert.Equal(t, `{"status":"DOWN"}`, results[0].Detail)
+}
+
+func Test_RetryChecker_StopsOnCancel(t *testing.T) {
+	checker := &sequenceChecker{statuses: []string{StatusDown, StatusDown, StatusDown}}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	results := NewRetryChecker(checker, 2, time.Minute).Check(ctx)
+
+	require.Equal(t, 1, len(results))
+	assert.Equal(t, StatusDown, results[0].Status)
+	assert.Equal(t, 1, checker.calls)
+}
+
+// sequenceChecker returns the statuses one after the other.
+type sequenceChecker struct {
+	statuses []string
+	calls    int
+	// detail of the failures, numbered by default
+	detail string
+}
+
+func (c *sequenceChecker) Check(ctx context.Context) []Result {
+	status := c.statuses[c.calls]
+	c.calls++
+	if status == StatusUp {
+		return []Result{NewResult("testEnv", "sequence", "sequence")}
+	}
+	result := NewResultFromError("testEnv", "sequence", "sequence", fmt.Errorf("error %v", c.calls))
+	result.Detail = fmt.Sprintf("detail %v", c.calls)
+	if c.detail != "" {
+		result.Detail = c.detail
+	}
+	return []Result{result}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+var (
+	RollupMinute = "minute"
+	RollupHour   = "hour"
+	RollupDay    = "day"
+)
+
+var rollupResolutions = map[string]time.Duration{
+	RollupMinute: time.Minute,
+	RollupHour:   time.Hour,
+	RollupDay:    24 * time.Hour,
+}
+
+// rollupDurationBuckets are the upper bounds in ms of the histogram,
+// from which the p95 of the durations is estimated.
+var rollupDurationBuckets = []int{10, 20, 50, 100, 200, 500, 1000, 2000, 5000, 10000, 30000}
+
+// ResultRollup aggregates the results of a check within one bucket of the resolution,
+// starting at Start (UTC). The durations are in ms.
+type ResultRollup struct {
+	Environment string    `json:"-" gorm:"primary_key" sql:"type:varchar(50)"`
+	Check       string    `json:"-" gorm:"primary_key" sql:"type:varchar(50)"`
+	Resolution  string    `json:"-" gorm:"primary_key" sql:"type:varchar(10)"`
+	Start       time.Time `json:"start" gorm:"primary_key"`
+	Up          int       `json:"up"`
+	Degraded    int       `json:"degraded"`
+	Down        int       `json:"down"`
+	Maintenance int       `json:"maintenance"`
+	MinDuration int       `json:"minDuration"`
+	AvgDuration int       `json:"avgDuration" gorm:"-"`
+	MaxDuration int       `json:"maxDuration"`
+	P95Duration int       `json:"p95Duration" gorm:"-"`
+	SumDuration int64     `json:"-"`
+	Histogram   string    `json:"-"`
+}
+
+func (r *ResultRollup) count() int {
+	return r.Up + r.Degraded + r.Down
+}
+
+// add aggregates the result into the rollup.
+func (r *ResultRollup) add(result Result) {
+	switch result.Status {
+	case StatusUp:
+		r.Up++
+	case StatusDegraded:
+		r.Degraded++
+	default:
+		r.Down++
+	}
+	if result.Maintenance {
+		r.Maintenance++
+	}
+
+	if r.count() == 1 || result.Duration < r.MinDuration {
+		r.MinDuration = result.Duration
+	}
+	if result.Duration > r.MaxDuration {
+		r.MaxDuration = result.Duration
+	}
+	r.SumDuration += int64(result.Duration)
+
+	histogram := r.histogram()
+	i := 0
+	for i < len(rollupDurationBuckets) && result.Duration > rollupDurationBuckets[i] {
+		i++
+	}
+	histogram[i]++
+	counts := make([]string, len(histogram))
+	for i, c := range histogram {
+		counts[i] = strconv.Itoa(c)
+	}
+	r.Histogram = strings.Join(counts, ",")
+}
+
+// histogram returns the counts of the duration buckets, the last one is for the larger durations.
+func (r *ResultRollup) histogram() []int {
+	histogram := make([]int, len(rollupDurationBuckets)+1)
+	if r.Histogram == "" {
+		return histogram
+	}
+	for i, c := range strings.Split(r.Histogram, ",") {
+		if i < len(histogram) {
+			histogram[i], _ = strconv.Atoi(c)
+		}
+	}
+	return histogram
+}
+
+// AfterFind calculates the average and estimates the p95 of the durations
+// by the upper bound of the histogram bucket, limited to the max duration.
+func (r *ResultRollup) AfterFind() error {
+	count := r.count()
+	if count == 0 {
+		return nil
+	}
+	r.AvgDuration = int(r.SumDuration / int64(count))
+
+	r.P95Duration = r.MaxDuration
+	seen := 0
+	for i, c := range r.histogram() {
+		seen += c
+		if seen*100 >= count*95 {
+			if i < len(rollupDurationBuckets) && rollupDurationBuckets[i] < r.MaxDuration {
+				r.P95Duration = rollupDurationBuckets[i]
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// updateRollups aggregates the result into the buckets of all resolutions.
+func (store *Store) updateRollups(result Result) error {
+	for resolution, d := range rollupResolutions {
+		r := &ResultRollup{}
+		err := store.db.
+			Where(`environment = ? AND "check" = ? AND resolution = ? AND start = ?`,
+				result.Environment, result.Check, resolution, result.Timestamp.UTC().Truncate(d)).
+			First(r).
+			Error
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return errors.Wrap(err, "query rollup")
+		}
+		if err == gorm.ErrRecordNotFound {
+			r = &ResultRollup{
+				Environment: result.Environment,
+				Check:       result.Check,
+				Resolution:  resolution,
+				Start:       result.Timestamp.UTC().Truncate(d),
+			}
+		}
+
+		r.add(result)
+		err = store.db.Save(r).Error
+		if err != nil {
+			return errors.Wrap(err, "save rollup")
+		}
+	}
+	return nil
+}
+
+// History returns the rollups of the check for the resolution, which start within from to.
+func (store *Store) History(environment, check, resolution string, from, to time.Time) (rollups []*ResultRollup, err error) {
+	rollups = []*ResultRollup{}
+	err = store.db.
+		Where(`environment = ? AND "check" = ? AND resolution = ? AND start >= ? AND start < ?`,
+			environment, check, resolution, from.UTC().Truncate(rollupResolutions[resolution]), to.UTC()).
+		Order("start").
+		Find(&rollups).
+		Error
+	return
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ResultRollup_Add(t *testing.T) {
+	r := &ResultRollup{}
+	for i := 1; i <= 20; i++ {
+		result := upResult("check1")
+		result.Duration = 40
+		if i == 20 {
+			result.Status = StatusDown
+			result.Duration = 3000
+		}
+		r.add(result)
+	}
+	require.NoError(t, r.AfterFind())
+
+	assert.Equal(t, 19, r.Up)
+	assert.Equal(t, 1, r.Down)
+	assert.Equal(t, 40, r.MinDuration)
+	assert.Equal(t, 3000, r.MaxDuration)
+	assert.Equal(t, (19*40+3000)/20, r.AvgDuration)
+	// 95% of the durations are within the bucket up to 50ms
+	assert.Equal(t, 50, r.P95Duration)
+
+	// the p95 is limited to the max duration
+	for i := 0; i < 2; i++ {
+		result := downResult("check1")
+		result.Duration = 3000
+		r.add(result)
+	}
+	require.NoError(t, r.AfterFind())
+	assert.Equal(t, 3000, r.P95Duration)
+}
+
+func Test_Store_History(t *testing.T) {
+	cfg := testConfig(t)
+	store, err := NewStore(cfg, &NotifyMock{})
+	require.NoError(t, err)
+	defer store.Close()
+
+	start := time.Date(2026, 9, 1, 10, 0, 0, 0, time.UTC)
+	for i, offset := range []time.Duration{
+		10 * time.Second, 30 * time.Second, 70 * time.Second, 2 * time.Hour, 25 * time.Hour,
+	} {
+		result := upResult("check1")
+		if i == 1 {
+			result = degradedResult("check1")
+		}
+		result.Timestamp = start.Add(offset)
+		result.Duration = 100 * (i + 1)
+		require.NoError(t, store.InsertResult(result))
+	}
+	require.NoError(t, store.InsertResult(upResult("check2")))
+
+	minutes, err := store.History("testEnv", "check1", RollupMinute, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 2, len(minutes))
+	assert.True(t, start.Equal(minutes[0].Start))
+	assert.Equal(t, 1, minutes[0].Up)
+	assert.Equal(t, 1, minutes[0].Degraded)
+	assert.Equal(t, 100, minutes[0].MinDuration)
+	assert.Equal(t, 150, minutes[0].AvgDuration)
+	assert.Equal(t, 200, minutes[0].MaxDuration)
+
+	hours, err := store.History("testEnv", "check1", RollupHour, start, start.Add(24*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 2, len(hours))
+	assert.Equal(t, 3, hours[0].Up+hours[0].Degraded+hours[0].Down)
+
+	days, err := store.History("testEnv", "check1", RollupDay, start, start.Add(48*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 2, len(days))
+	assert.True(t, time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC).Equal(days[0].Start))
+	assert.Equal(t, 4, days[0].Up+days[0].Degraded)
+	assert.Equal(t, 1, days[1].Up)
+}
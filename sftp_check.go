@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"sync"
 	"time"
 
@@ -28,7 +30,7 @@ type SftpCheck struct {
 	mutex sync.Mutex
 }
 
-func NewSftpCheck(environmentID, checkID, name string, params map[string]string) (*SftpCheck, error) {
+func NewSftpCheck(environmentID, checkID, name string, timeout time.Duration, params map[string]string) (*SftpCheck, error) {
 	c := &SftpCheck{
 		environmentID: environmentID,
 		checkID:       checkID,
@@ -38,15 +40,11 @@ func NewSftpCheck(environmentID, checkID, name string, params map[string]string)
 		testfile:      params["testfile"],
 	}
 
-	if t, exist := params["timeout"]; exist {
-		d, err := time.ParseDuration(t)
-		if err != nil {
-			return nil, err
-		}
-		c.timeout = d
-	} else {
-		c.timeout = time.Second * 10
+	d, err := checkTimeout(timeout, params)
+	if err != nil {
+		return nil, err
 	}
+	c.timeout = d
 
 	if c.port == "" {
 		c.port = "22"
@@ -62,27 +60,23 @@ func NewSftpCheck(environmentID, checkID, name string, params map[string]string)
 		return nil, err
 	}
 
+	clientConfig.Timeout = c.timeout
 	c.clientConfig = clientConfig
 
 	return c, nil
 }
 
-func (c *SftpCheck) Check() []Result {
+func (c *SftpCheck) Check(ctx context.Context) []Result {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
 	mainResult := NewResult(c.environmentID, c.checkID, c.name)
 
-	var err error
-	done := make(chan bool, 1)
-	go func() {
-		err = c.connectTest()
-		done <- true
-	}()
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
 
-	select {
-	case <-done:
-	case <-time.After(c.timeout):
+	err := c.connectTest(ctx)
+	if ctx.Err() == context.DeadlineExceeded {
 		err = fmt.Errorf("sftp timeout after %v on %v:%v", c.timeout, c.host, c.port)
 	}
 
@@ -98,11 +92,24 @@ func (c *SftpCheck) Check() []Result {
 	return results
 }
 
-func (c *SftpCheck) connectTest() error {
-	conn, err := ssh.Dial("tcp", c.host+":"+c.port, c.clientConfig)
+func (c *SftpCheck) connectTest(ctx context.Context) error {
+	addr := net.JoinHostPort(c.host, c.port)
+	dialer := &net.Dialer{}
+	tcpConn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect with ssh")
+	}
+	defer tcpConn.Close()
+
+	// abort all pending network io, if the context is done
+	stop := context.AfterFunc(ctx, func() { tcpConn.Close() })
+	defer stop()
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(tcpConn, addr, c.clientConfig)
 	if err != nil {
 		return errors.Wrap(err, "failed to connect with ssh")
 	}
+	conn := ssh.NewClient(sshConn, chans, reqs)
 	defer conn.Close()
 
 	client, err := sftp.NewClient(conn)
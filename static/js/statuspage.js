@@ -23,6 +23,8 @@ app.factory('store', function($rootScope, $http, $interval) {
     store.data.selectedEnv = undefined;
     store.data.checks = [{name: "Loading .."}];
     store.data.downtimes = [];
+    store.data.maintenance = [];
+    store.data.notificationFailures = [];
     store.data.sinceLastCheckUpdate = undefined;
     store.checkUpdateTimestamp = undefined;
     store.selectedEnvId = undefined;
@@ -63,6 +65,8 @@ app.factory('store', function($rootScope, $http, $interval) {
                 
                 store.checkUpdateTimestamp = Date.now();
                 store.data.downtimes = data.downtimes;
+                store.data.maintenance = data.maintenance;
+                store.data.notificationFailures = data.notificationFailures;
                 store.data.sinceLastCheckUpdate = 0;
                 data.checks.sort(compareByName);
                 store.data.checks = data.checks;
@@ -146,6 +150,9 @@ app.run(function($rootScope, store) {
         if (s == "ERROR") {
             return "bg-danger";
         }
+        if (s == "MAINTENANCE") {
+            return "bg-maintenance";
+        }
         return "bg-info";
     }
 
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/jinzhu/gorm"
@@ -11,7 +13,11 @@ import (
 
 type Store struct {
 	db       *gorm.DB
+	cfg      *Config
 	notifyer Notifyer
+
+	// wakes up runDeliveries, if the notifications are delivered in the background
+	deliveryWakeup chan bool
 }
 
 func NewStore(cfg *Config, notifyer Notifyer) (*Store, error) {
@@ -32,14 +38,27 @@ func NewStore(cfg *Config, notifyer Notifyer) (*Store, error) {
 	gormdb.DB().SetMaxOpenConns(cfg.Worker + 1)
 	gormdb.SingularTable(true)
 
-	err = gormdb.AutoMigrate(&Result{}, &CheckStatus{}, &Downtime{}).Error
+	s := &Store{
+		db:       gormdb,
+		cfg:      cfg,
+		notifyer: notifyer,
+	}
+
+	if cfg.retentionEnabled() {
+		err = s.enableIncrementalVacuum()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = gormdb.AutoMigrate(&Result{}, &CheckStatus{}, &Downtime{}, &MaintenanceWindow{}, &NotificationDelivery{}, &ResultRollup{}, &StatusChange{}).Error
 	if err != nil {
 		return nil, errors.Wrap(err, "schema migration")
 	}
 
-	s := &Store{
-		db:       gormdb,
-		notifyer: notifyer,
+	err = s.convertTimesToUTC()
+	if err != nil {
+		return nil, errors.Wrap(err, "converting times to UTC")
 	}
 
 	err = s.updateChecks(cfg)
@@ -50,13 +69,49 @@ func NewStore(cfg *Config, notifyer Notifyer) (*Store, error) {
 	return s, nil
 }
 
+// timeColumns are the columns of type time.Time by table.
+var timeColumns = map[string][]string{
+	"result":                {"timestamp"},
+	"check_status":          {"updated"},
+	"downtime":              {"start", "end", "comment_time", "ack_time", "down_notify_time", "degraded_notify_time", "last_reminder_time", "recover_notify_time"},
+	"maintenance_window":    {"start", "end"},
+	"notification_delivery": {"created", "next_attempt", "sent"},
+	"status_change":         {"time"},
+}
+
+// convertTimesToUTC converts the times of an existing database to UTC once.
+// Sqlite compares the stored times as strings including their offset,
+// so all times have to be stored in UTC to be comparable.
+func (store *Store) convertTimesToUTC() error {
+	var version int
+	err := store.db.DB().QueryRow(`PRAGMA user_version`).Scan(&version)
+	if err != nil {
+		return errors.Wrap(err, "query user_version")
+	}
+	if version >= 1 {
+		return nil
+	}
+
+	for table, columns := range timeColumns {
+		for _, column := range columns {
+			err = store.db.Exec(fmt.Sprintf(
+				`UPDATE %v SET "%v" = strftime('%%Y-%%m-%%d %%H:%%M:%%f+00:00', "%v") WHERE "%v" NOT LIKE '%%+00:00'`,
+				table, column, column, column)).Error
+			if err != nil {
+				return errors.Wrapf(err, "convert %v.%v", table, column)
+			}
+		}
+	}
+	return store.db.Exec(`PRAGMA user_version = 1`).Error
+}
+
 func (store *Store) Close() error {
 	return store.db.Close()
 }
 
 func (store *Store) updateChecks(cfg *Config) error {
 	allKeysInConfig := map[string]string{}
-	for _, e := range cfg.Environments {
+	for _, e := range cfg.Envs() {
 		for _, c := range e.Checks {
 			info := &CheckStatus{}
 			if store.db.Where(`environment = ? AND "check" = ?`, e.Id, c.Id).First(info).RecordNotFound() {
@@ -96,11 +151,48 @@ func (store *Store) updateChecks(cfg *Config) error {
 			}
 		}
 	}
+
+	// the open downtimes of removed checks are closed without a recover notification,
+	// so that they are neither reminded nor escalated any more
+	openDowns := []*Downtime{}
+	err = store.db.Where(`recovered = 0`).Find(&openDowns).Error
+	if err != nil {
+		return errors.Wrap(err, "query downtimes")
+	}
+	for _, d := range openDowns {
+		if _, existInConfig := allKeysInConfig[d.Environment+"/"+d.Check]; existInConfig {
+			continue
+		}
+		err := store.db.Model(d).Updates(map[string]interface{}{
+			"recovered":           true,
+			"end":                 time.Now().UTC(),
+			"recover_notify_sent": true,
+		}).Error
+		if err != nil {
+			return errors.Wrap(err, "close downtime")
+		}
+	}
 	return nil
 }
 
+// InsertResult stores the result and updates the status and the downtimes of the check.
+// Results of checks, which were removed from the configuration in the meantime, are ignored.
 func (store *Store) InsertResult(result Result) error {
-	err := store.db.Create(&result).Error
+	env, _ := store.cfg.EnvById(result.Environment)
+	if !env.hasCheck(result.Check) {
+		log.Printf("ignoring result of removed check %v/%v\n", result.Environment, result.Check)
+		return nil
+	}
+
+	// the times are stored in UTC to be comparable
+	result.Timestamp = result.Timestamp.UTC()
+	inMaintenance, err := store.inMaintenance(result.Environment, result.Check, result.Timestamp)
+	if err != nil {
+		return errors.Wrap(err, "query maintenance")
+	}
+	result.Maintenance = inMaintenance
+
+	err = store.db.Create(&result).Error
 	if err != nil {
 		return errors.Wrap(err, "create result")
 	}
@@ -110,6 +202,11 @@ func (store *Store) InsertResult(result Result) error {
 		return errors.Wrap(err, "updateCheckStatus")
 	}
 
+	err = store.updateRollups(result)
+	if err != nil {
+		return errors.Wrap(err, "updateRollups")
+	}
+
 	err = store.updateDowntimes(result)
 	if err != nil {
 		return errors.Wrap(err, "updateDowntimes")
@@ -134,6 +231,7 @@ func (store *Store) updateCheckStatus(result Result) error {
 	checkStatus.Duration = result.Duration
 	checkStatus.Updated = result.Timestamp
 	checkStatus.LastResultId = result.Id
+	checkStatus.Maintenance = result.Maintenance
 
 	err = store.db.Where(`environment = ? AND "check" = ?`, checkStatus.Environment, checkStatus.Check).
 		Save(&checkStatus).Error
@@ -161,27 +259,57 @@ func (store *Store) updateDowntimes(result Result) error {
 		return nil
 	}
 
-	if result.Status == StatusUp {
-		d.Recovered = true
-		d.End = time.Now()
-	} else {
-
-		if !openDowntimeLoaded {
-			d.Environment = result.Environment
-			d.Check = result.Check
-			d.Name = result.Name
-			d.Start = time.Now()
-		}
-		d.FailCount++
+	// an open downtime is only updated in the columns of the result,
+	// because it may be acknowledged or notified in the meantime
+	now := time.Now().UTC()
+	statusChanged := false
+	if !openDowntimeLoaded {
+		statusChanged = true
+		d.Environment = result.Environment
+		d.Check = result.Check
+		d.Name = result.Name
+		d.Start = now
+		d.Maintenance = result.Maintenance
+		d.FailCount = 1
 		d.LastResultId = result.Id
+		d.Status = result.Status
 		d.Message = result.Message
+		err = store.db.Create(d).Error
+	} else if result.Status == StatusUp {
+		err = store.db.Model(d).Updates(map[string]interface{}{
+			"recovered": true,
+			"end":       now,
+		}).Error
+	} else {
+		statusChanged = d.Status != result.Status
+		columns := map[string]interface{}{
+			"fail_count":     d.FailCount + 1,
+			"last_result_id": result.Id,
+			"status":         result.Status,
+			"message":        result.Message,
+		}
+		// a downtime, which changes to degraded, is notified as degraded again
+		if d.Status != StatusDegraded && result.Status == StatusDegraded {
+			columns["degraded_notify_sent"] = false
+		}
+		err = store.db.Model(d).Updates(columns).Error
 	}
-
-	err = store.db.Save(d).Error
 	if err != nil {
 		return errors.Wrap(err, "save downtime")
 	}
 
+	if statusChanged {
+		err = store.db.Create(&StatusChange{DowntimeId: d.Id, Status: result.Status, Time: now}).Error
+		if err != nil {
+			return errors.Wrap(err, "save status change")
+		}
+	}
+
+	if result.Maintenance {
+		// the notifications are sent with the next result after the maintenance
+		return nil
+	}
+
 	err = store.checkForDownNotifications(result.Environment)
 	if err != nil {
 		return errors.Wrap(err, "CheckForDownNotifications")
@@ -196,37 +324,68 @@ func (store *Store) updateDowntimes(result Result) error {
 }
 
 func (store *Store) checkForDownNotifications(environment string) error {
-	now := time.Now()
+	now := time.Now().UTC()
 
-	downs := []*Downtime{}
+	openDowns := []*Downtime{}
 	err := store.db.
-		Where(`environment = ? AND recovered = 0 AND down_notify_sent == 0 AND fail_count >= 2`, environment).
-		Find(&downs).
+		Where(`environment = ? AND recovered = 0 AND acknowledged == 0 AND
+			(down_notify_sent == 0 OR (status = ? AND degraded_notify_sent == 0))`, environment, StatusDegraded).
+		Find(&openDowns).
 		Error
 	if err != nil {
 		return err
 	}
-	if len(downs) == 0 {
-		return nil
+
+	// only notify the downtimes, which have reached the threshold of their check
+	// and are not within a maintenance window
+	env, _ := store.cfg.EnvById(environment)
+	downs := []*Downtime{}
+	degraded := []*Downtime{}
+	for _, d := range openDowns {
+		if !env.failThresholdReached(d, now) {
+			continue
+		}
+		inMaintenance, err := store.inMaintenance(environment, d.Check, now)
+		if err != nil {
+			return err
+		}
+		if inMaintenance {
+			continue
+		}
+		if d.Status != StatusDegraded {
+			downs = append(downs, d)
+		} else if !d.DegradedNotifySent {
+			degraded = append(degraded, d)
+		}
 	}
 
-	// find all downs, with any fail count
-	err = store.db.
-		Where(`environment = ? AND recovered = 0 AND down_notify_sent == 0`, environment).
-		Find(&downs).
-		Error
-	if err != nil {
-		return err
+	for _, d := range downs {
+		err := store.db.Model(d).Updates(map[string]interface{}{
+			"down_notify_sent": true,
+			"down_notify_time": now,
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+	for _, d := range degraded {
+		err := store.db.Model(d).Updates(map[string]interface{}{
+			"degraded_notify_sent": true,
+			"degraded_notify_time": now,
+		}).Error
+		if err != nil {
+			return err
+		}
 	}
 
-	err = store.notifyer.NotifyDown(environment, downs)
-	if err != nil {
-		return err
+	if len(downs) > 0 {
+		err = store.enqueueNotifications(environment, NotificationDown, immediateTargets(env, StatusDown), downs, now)
+		if err != nil {
+			return err
+		}
 	}
-	for _, d := range downs {
-		d.DownNotifySent = true
-		d.DownNotifyTime = now
-		err := store.db.Save(d).Error
+	if len(degraded) > 0 {
+		err = store.enqueueNotifications(environment, NotificationDegraded, immediateTargets(env, StatusDegraded), degraded, now)
 		if err != nil {
 			return err
 		}
@@ -234,12 +393,24 @@ func (store *Store) checkForDownNotifications(environment string) error {
 	return nil
 }
 
+// immediateTargets returns the notification targets for the status.
+// The targets with an escalation delay are notified by checkForEscalations.
+func immediateTargets(env Env, status string) []Notification {
+	targets := []Notification{}
+	for _, n := range env.Notifications {
+		if n.EscalateAfter <= 0 && n.receives(status) {
+			targets = append(targets, n)
+		}
+	}
+	return targets
+}
+
 func (store *Store) checkForRecoverNotifications(environment string) error {
-	now := time.Now()
+	now := time.Now().UTC()
 
 	ups := []*Downtime{}
 	err := store.db.
-		Where(`environment = ? AND down_notify_sent = 1 AND recovered = 1 AND recover_notify_sent == 0`, environment).
+		Where(`environment = ? AND (down_notify_sent = 1 OR degraded_notify_sent = 1) AND recovered = 1 AND recover_notify_sent == 0`, environment).
 		Find(&ups).
 		Error
 	if err != nil {
@@ -249,14 +420,143 @@ func (store *Store) checkForRecoverNotifications(environment string) error {
 		return nil
 	}
 
-	err = store.notifyer.NotifyRecovered(environment, ups)
+	for _, d := range ups {
+		err := store.db.Model(d).Updates(map[string]interface{}{
+			"recover_notify_sent": true,
+			"recover_notify_time": now,
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	// the targets get the recovery of the downtimes, they were notified of
+	env, _ := store.cfg.EnvById(environment)
+	for _, n := range env.Notifications {
+		notified := []*Downtime{}
+		for _, d := range ups {
+			if n.notifiedOf(d) {
+				notified = append(notified, d)
+			}
+		}
+		if len(notified) == 0 {
+			continue
+		}
+		err := store.enqueueNotifications(environment, NotificationRecovered, []Notification{n}, notified, now)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkForEscalations notifies the targets with an escalation delay
+// of the notified downtimes, which are open and not acknowledged for longer than the delay.
+func (store *Store) checkForEscalations(now time.Time) error {
+	return store.forEachEnv(now, store.checkForEnvEscalations)
+}
+
+// checkForReminders reminds the notification targets with a reminder interval
+// of the notified downtimes, which are still open and not acknowledged.
+func (store *Store) checkForReminders(now time.Time) error {
+	return store.forEachEnv(now, store.checkForEnvReminders)
+}
+
+func (store *Store) forEachEnv(now time.Time, check func(env Env, now time.Time) error) error {
+	envErrors := []string{}
+	now = now.UTC()
+	for _, env := range store.cfg.Envs() {
+		err := check(env, now)
+		if err != nil {
+			envErrors = append(envErrors, fmt.Sprintf("%v: %v", env.Id, err))
+		}
+	}
+	if len(envErrors) > 0 {
+		return errors.New(strings.Join(envErrors, ", "))
+	}
+	return nil
+}
+
+func (store *Store) checkForEnvEscalations(env Env, now time.Time) error {
+	downs, err := store.openNotifiedDowntimes(env.Id, now)
 	if err != nil {
 		return err
 	}
-	for _, d := range ups {
-		d.RecoverNotifySent = true
-		d.RecoverNotifyTime = now
-		err := store.db.Save(d).Error
+
+	escalated := map[uint]time.Duration{}
+	due := make([][]*Downtime, len(env.Notifications))
+	for i, n := range env.Notifications {
+		for _, d := range downs {
+			if n.escalationDue(d, now) {
+				due[i] = append(due[i], d)
+				if n.EscalateAfter > escalated[d.Id] {
+					escalated[d.Id] = n.EscalateAfter
+				}
+			}
+		}
+	}
+
+	// the escalation is recorded before sending, so that a concurrent recovery is also sent
+	// to the escalated targets. Only the escalation column is updated and recovered downtimes are skipped.
+	for _, d := range downs {
+		escalateAfter, exist := escalated[d.Id]
+		if !exist {
+			continue
+		}
+		db := store.db.Model(d).Where(`recovered = 0`).UpdateColumn("escalated_after", escalateAfter)
+		if db.Error != nil {
+			return db.Error
+		}
+		if db.RowsAffected == 0 {
+			delete(escalated, d.Id)
+		}
+	}
+
+	for i, n := range env.Notifications {
+		downtimes := []*Downtime{}
+		for _, d := range due[i] {
+			if _, exist := escalated[d.Id]; exist {
+				downtimes = append(downtimes, d)
+			}
+		}
+		if len(downtimes) == 0 {
+			continue
+		}
+		err := store.enqueueNotifications(env.Id, NotificationEscalation, []Notification{n}, downtimes, now)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (store *Store) checkForEnvReminders(env Env, now time.Time) error {
+	downs, err := store.openNotifiedDowntimes(env.Id, now)
+	if err != nil {
+		return err
+	}
+
+	reminded := map[uint]*Downtime{}
+	for _, n := range env.Notifications {
+		due := []*Downtime{}
+		for _, d := range downs {
+			if n.reminderDue(d, now) {
+				due = append(due, d)
+				reminded[d.Id] = d
+			}
+		}
+		if len(due) == 0 {
+			continue
+		}
+		err := store.enqueueNotifications(env.Id, NotificationReminder, []Notification{n}, due, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	// only the reminder column, the downtime may be recovered in the meantime
+	for _, d := range reminded {
+		err := store.db.Model(d).Where(`recovered = 0`).UpdateColumn("last_reminder_time", now).Error
 		if err != nil {
 			return err
 		}
@@ -264,17 +564,103 @@ func (store *Store) checkForRecoverNotifications(environment string) error {
 	return nil
 }
 
-func (store *Store) Downtimes(environment string) (results []*Downtime, err error) {
+// openNotifiedDowntimes returns the open downtimes of the configured checks, which are notified,
+// not acknowledged and not within a maintenance window.
+func (store *Store) openNotifiedDowntimes(environment string, now time.Time) ([]*Downtime, error) {
+	openDowns := []*Downtime{}
+	err := store.db.
+		Where(`environment = ? AND recovered = 0 AND down_notify_sent == 1 AND acknowledged == 0`, environment).
+		Find(&openDowns).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	env, _ := store.cfg.EnvById(environment)
+	downs := []*Downtime{}
+	for _, d := range openDowns {
+		if !env.hasCheck(d.Check) {
+			continue
+		}
+		inMaintenance, err := store.inMaintenance(environment, d.Check, now)
+		if err != nil {
+			return nil, err
+		}
+		if !inMaintenance {
+			downs = append(downs, d)
+		}
+	}
+	return downs, nil
+}
+
+// Downtimes returns a page of the downtimes of the environment, the open ones first and then the newest.
+func (store *Store) Downtimes(environment string, offset, limit int) (results []*Downtime, err error) {
 	err = store.db.
 		Where(`environment = ?`, environment).
-		Order("recovered ASC, start DESC").
-		Limit(30).
+		Order("recovered ASC, start DESC, id DESC").
+		Offset(offset).
+		Limit(limit).
 		Find(&results).
 		Error
 
 	return
 }
 
+// CountOpenDowntimes returns the number of downtimes of the environment, which are not recovered.
+func (store *Store) CountOpenDowntimes(environment string) (count int, err error) {
+	err = store.db.
+		Model(&Downtime{}).
+		Where(`environment = ? AND recovered = 0`, environment).
+		Count(&count).
+		Error
+	return
+}
+
+// AckDowntime records, that someone takes care of the downtime.
+// An acknowledged downtime is not notified any more.
+func (store *Store) AckDowntime(environment string, id int, by, note string, t time.Time) (*Downtime, bool, error) {
+	d, found, err := store.downtime(environment, id)
+	if !found || err != nil {
+		return nil, found, err
+	}
+
+	// only the ack columns, the downtime may be updated by a new result in the meantime
+	err = store.db.Model(d).Updates(map[string]interface{}{
+		"acknowledged": true,
+		"ack_by":       by,
+		"ack_note":     note,
+		"ack_time":     t.UTC(),
+	}).Error
+	return d, true, err
+}
+
+func (store *Store) CommentDowntime(environment string, id int, by, comment string, t time.Time) (*Downtime, bool, error) {
+	d, found, err := store.downtime(environment, id)
+	if !found || err != nil {
+		return nil, found, err
+	}
+
+	// only the comment columns, the downtime may be updated by a new result in the meantime
+	err = store.db.Model(d).Updates(map[string]interface{}{
+		"comment":      comment,
+		"comment_by":   by,
+		"comment_time": t.UTC(),
+	}).Error
+	return d, true, err
+}
+
+func (store *Store) downtime(environment string, id int) (*Downtime, bool, error) {
+	d := &Downtime{}
+	err := store.db.
+		Where(`environment = ? AND id = ?`, environment, id).
+		First(d).
+		Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, false, nil
+	}
+	return d, err == nil, err
+}
+
 func (store *Store) Status(environment string) (statusList []*CheckStatus, err error) {
 	err = store.db.
 		Where(`environment = ?`, environment).
@@ -296,10 +682,47 @@ func (store *Store) Result(id int) (*Result, bool, error) {
 	return res, found, err
 }
 
-func (store *Store) CountGoodAndBad(s []*CheckStatus) (good, bad int) {
+// ResultQuery filters the results of a check.
+type ResultQuery struct {
+	From     time.Time
+	To       time.Time
+	Statuses []string
+	// Before is the cursor of the page, only the results with a lower id are returned
+	Before        uint
+	Limit         int
+	IncludeDetail bool
+}
+
+// Results returns the results of the check, which match the query, the newest first.
+func (store *Store) Results(environment, check string, q ResultQuery) (results []*Result, err error) {
+	db := store.db.
+		Where(`environment = ? AND "check" = ? AND timestamp >= ? AND timestamp < ?`, environment, check, q.From.UTC(), q.To.UTC())
+	if len(q.Statuses) > 0 {
+		db = db.Where(`status IN (?)`, q.Statuses)
+	}
+	if q.Before > 0 {
+		db = db.Where(`id < ?`, q.Before)
+	}
+	if !q.IncludeDetail {
+		db = db.Select(`id, environment, "check", name, status, message, duration, timestamp, maintenance`)
+	}
+
+	results = []*Result{}
+	err = db.
+		Order("id DESC").
+		Limit(q.Limit).
+		Find(&results).
+		Error
+	return
+}
+
+// CountGoodAndBad counts the checks by status. Failing checks in maintenance are not counted as bad.
+func (store *Store) CountGoodAndBad(s []*CheckStatus) (good, bad, maintenance int) {
 	for _, res := range s {
 		if res.Status == StatusUp {
 			good++
+		} else if res.Maintenance {
+			maintenance++
 		} else {
 			bad++
 		}
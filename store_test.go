@@ -71,6 +71,78 @@ func Test_Store_CheckStatus_UpdateNames(t *testing.T) {
 	Equal(t, "updated name", changedStatus.Name)
 }
 
+func Test_Store_UpdateChecks_Reload(t *testing.T) {
+	cfg := testConfig(t)
+	store, err := NewStore(cfg, NewNotificationGateway(cfg))
+	NoError(t, err)
+	defer store.Close()
+
+	envs := []Env{
+		Env{
+			Id: "testEnv",
+			Checks: []Check{
+				Check{Id: "check2", Name: "Check 2"},
+				Check{Id: "check3", Name: "Check 3"},
+			},
+		},
+	}
+	cfg.SetEnvs(envs)
+	NoError(t, store.updateChecks(cfg))
+
+	s, err := store.Status("testEnv")
+	NoError(t, err)
+	Equal(t, 2, len(s))
+	Equal(t, "check2", s[0].Check)
+	Equal(t, "check3", s[1].Check)
+
+	NoError(t, store.InsertResult(upResult("check3")))
+
+	// a result of the removed check, which was still running, is ignored
+	removed := downResult("check1")
+	NoError(t, store.InsertResult(removed))
+	_, found, err := store.Result(int(removed.Id))
+	NoError(t, err)
+	False(t, found)
+	s, err = store.Status("testEnv")
+	NoError(t, err)
+	Equal(t, 2, len(s))
+}
+
+func Test_Store_UpdateChecks_ReloadClosesDowntimes(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Environments[0].Notifications = []Notification{
+		{Type: "slack", RemindEvery: time.Hour},
+		{Type: "pagerduty", EscalateAfter: 10 * time.Minute},
+	}
+	notifyMock := &NotifyMock{}
+	store, err := NewStore(cfg, notifyMock)
+	NoError(t, err)
+	defer store.Close()
+
+	NoError(t, store.InsertResult(downResult("check1")))
+	NoError(t, store.InsertResult(downResult("check1")))
+	Equal(t, []string{"slack"}, notifyMock.downTypes)
+	notifyMock.reset()
+
+	envs := []Env{{Id: "testEnv", Notifications: cfg.Environments[0].Notifications, Checks: []Check{{Id: "check2", Name: "Check 2"}}}}
+	cfg.SetEnvs(envs)
+
+	// the removed check is skipped, even before its downtime is closed
+	NoError(t, store.checkForEscalations(time.Now().Add(11*time.Minute)))
+	NoError(t, store.checkForReminders(time.Now().Add(2*time.Hour)))
+	notifyMock.AssertNoNotifications(t)
+
+	NoError(t, store.updateChecks(cfg))
+	open, err := store.CountOpenDowntimes("testEnv")
+	NoError(t, err)
+	Equal(t, 0, open)
+
+	NoError(t, store.InsertResult(upResult("check2")))
+	NoError(t, store.checkForEscalations(time.Now().Add(11*time.Minute)))
+	NoError(t, store.checkForReminders(time.Now().Add(2*time.Hour)))
+	notifyMock.AssertNoNotifications(t)
+}
+
 func Test_Store_DowntimeNotifications(t *testing.T) {
 	cfg := testConfig(t)
 	notifyMock := &NotifyMock{}
@@ -99,13 +171,14 @@ func Test_Store_DowntimeNotifications(t *testing.T) {
 	NoError(t, store.InsertResult(downResult("check2")))
 	NoError(t, store.InsertResult(upResult("check2")))
 
-	downtimes, err := store.Downtimes("testEnv")
+	downtimes, err := store.Downtimes("testEnv", 0, 30)
 	NoError(t, err)
 
 	// first downtime is the one, which is not recovered
 	d := downtimes[0]
 	Equal(t, "check1", d.Check)
 	Equal(t, 1, d.FailCount)
+	Equal(t, StatusDown, d.Status)
 	False(t, d.Recovered)
 
 	// second downtime is the newest one
@@ -121,6 +194,386 @@ func Test_Store_DowntimeNotifications(t *testing.T) {
 	True(t, d.Recovered)
 }
 
+func Test_Store_DowntimeNotifications_Threshold(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Environments[0].FailThreshold = 3
+	cfg.Environments[0].Checks[1].FailThreshold = 1
+	notifyMock := &NotifyMock{}
+	store, err := NewStore(cfg, notifyMock)
+	NoError(t, err)
+	defer store.Close()
+
+	// check2 notifies on the first failure
+	NoError(t, store.InsertResult(downResult("check2")))
+	Equal(t, 1, len(notifyMock.downs))
+	Equal(t, "check2", notifyMock.downs[0].Check)
+	notifyMock.reset()
+
+	// check1 uses the threshold of the environment
+	NoError(t, store.InsertResult(downResult("check1")))
+	NoError(t, store.InsertResult(downResult("check1")))
+	notifyMock.AssertNoNotifications(t)
+	NoError(t, store.InsertResult(downResult("check1")))
+	Equal(t, 1, len(notifyMock.downs))
+	Equal(t, "check1", notifyMock.downs[0].Check)
+}
+
+func Test_Store_DowntimeNotifications_FailDuration(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Environments[0].Checks[0].FailDuration = 50 * time.Millisecond
+	notifyMock := &NotifyMock{}
+	store, err := NewStore(cfg, notifyMock)
+	NoError(t, err)
+	defer store.Close()
+
+	NoError(t, store.InsertResult(downResult("check1")))
+	NoError(t, store.InsertResult(downResult("check1")))
+	NoError(t, store.InsertResult(downResult("check1")))
+	notifyMock.AssertNoNotifications(t)
+
+	time.Sleep(60 * time.Millisecond)
+	NoError(t, store.InsertResult(downResult("check1")))
+	Equal(t, 1, len(notifyMock.downs))
+	Equal(t, 4, notifyMock.downs[0].FailCount)
+}
+
+func Test_Store_AckDowntime(t *testing.T) {
+	cfg := testConfig(t)
+	notifyMock := &NotifyMock{}
+	store, err := NewStore(cfg, notifyMock)
+	NoError(t, err)
+	defer store.Close()
+
+	NoError(t, store.InsertResult(downResult("check1")))
+	downtimes, err := store.Downtimes("testEnv", 0, 30)
+	NoError(t, err)
+	id := int(downtimes[0].Id)
+
+	_, found, err := store.AckDowntime("otherEnv", id, "alice", "", time.Now())
+	NoError(t, err)
+	False(t, found)
+
+	d, found, err := store.AckDowntime("testEnv", id, "alice", "looking into it", time.Now())
+	NoError(t, err)
+	True(t, found)
+	True(t, d.Acknowledged)
+	Equal(t, "alice", d.AckBy)
+	Equal(t, "looking into it", d.AckNote)
+
+	d, found, err = store.CommentDowntime("testEnv", id, "bob", "disk full", time.Now())
+	NoError(t, err)
+	True(t, found)
+	Equal(t, "disk full", d.Comment)
+	Equal(t, "bob", d.CommentBy)
+	True(t, d.Acknowledged)
+
+	// the acknowledged downtime is not notified
+	NoError(t, store.InsertResult(downResult("check1")))
+	NoError(t, store.InsertResult(downResult("check1")))
+	notifyMock.AssertNoNotifications(t)
+
+	downtimes, err = store.Downtimes("testEnv", 0, 30)
+	NoError(t, err)
+	Equal(t, 3, downtimes[0].FailCount)
+	Equal(t, "disk full", downtimes[0].Comment)
+	Equal(t, "looking into it", downtimes[0].AckNote)
+}
+
+func Test_Store_Reminders(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Environments[0].Notifications = []Notification{
+		{Type: "slack", RemindEvery: time.Hour},
+		{Type: "email", RemindEvery: 2 * time.Hour},
+		{Type: "webhook"},
+	}
+	notifyMock := &NotifyMock{}
+	store, err := NewStore(cfg, notifyMock)
+	NoError(t, err)
+	defer store.Close()
+
+	NoError(t, store.InsertResult(downResult("check1")))
+	NoError(t, store.InsertResult(downResult("check1")))
+	Equal(t, 1, len(notifyMock.downs))
+	notifyMock.reset()
+
+	start := time.Now()
+	NoError(t, store.checkForReminders(start.Add(30*time.Minute)))
+	NoError(t, store.checkForReminders(start.Add(50*time.Minute)))
+	notifyMock.AssertNoNotifications(t)
+
+	NoError(t, store.checkForReminders(start.Add(61*time.Minute)))
+	Equal(t, []string{"slack"}, notifyMock.reminderTypes)
+	Equal(t, "check1", notifyMock.reminders[0].Check)
+	notifyMock.reset()
+
+	NoError(t, store.checkForReminders(start.Add(70*time.Minute)))
+	notifyMock.AssertNoNotifications(t)
+
+	NoError(t, store.checkForReminders(start.Add(121*time.Minute)))
+	Equal(t, []string{"slack", "email"}, notifyMock.reminderTypes)
+	notifyMock.reset()
+
+	// acknowledged downtimes are not reminded
+	downtimes, err := store.Downtimes("testEnv", 0, 30)
+	NoError(t, err)
+	_, _, err = store.AckDowntime("testEnv", int(downtimes[0].Id), "alice", "", time.Now())
+	NoError(t, err)
+	NoError(t, store.checkForReminders(start.Add(181*time.Minute)))
+	notifyMock.AssertNoNotifications(t)
+}
+
+func Test_Store_Reminders_ConcurrentRecovery(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Environments[0].Notifications = []Notification{{Type: "slack", RemindEvery: time.Hour}}
+	notifyMock := &NotifyMock{}
+	store, err := NewStore(cfg, notifyMock)
+	NoError(t, err)
+	defer store.Close()
+
+	NoError(t, store.InsertResult(downResult("check1")))
+	NoError(t, store.InsertResult(downResult("check1")))
+	notifyMock.reset()
+
+	// the check recovers, while the reminder is sent
+	notifyMock.onNotify = func() {
+		NoError(t, store.InsertResult(upResult("check1")))
+	}
+	start := time.Now()
+	NoError(t, store.checkForReminders(start.Add(61*time.Minute)))
+	Equal(t, 1, len(notifyMock.reminders))
+	Equal(t, 1, len(notifyMock.ups))
+
+	downtimes, err := store.Downtimes("testEnv", 0, 30)
+	NoError(t, err)
+	True(t, downtimes[0].Recovered)
+
+	notifyMock.reset()
+	NoError(t, store.checkForReminders(start.Add(121*time.Minute)))
+	notifyMock.AssertNoNotifications(t)
+}
+
+func Test_Store_Escalations_ConcurrentRecovery(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Environments[0].Notifications = []Notification{
+		{Type: "slack"},
+		{Type: "pagerduty", EscalateAfter: 10 * time.Minute},
+	}
+	notifyMock := &NotifyMock{}
+	store, err := NewStore(cfg, notifyMock)
+	NoError(t, err)
+	defer store.Close()
+
+	NoError(t, store.InsertResult(downResult("check1")))
+	NoError(t, store.InsertResult(downResult("check1")))
+	notifyMock.reset()
+
+	// the check recovers, while the escalation is sent
+	notifyMock.onNotify = func() {
+		NoError(t, store.InsertResult(upResult("check1")))
+	}
+	start := time.Now()
+	NoError(t, store.checkForEscalations(start.Add(11*time.Minute)))
+	Equal(t, []string{"pagerduty:check1"}, notifyMock.escalations)
+	Equal(t, []string{"slack", "pagerduty"}, notifyMock.upTypes)
+
+	downtimes, err := store.Downtimes("testEnv", 0, 30)
+	NoError(t, err)
+	True(t, downtimes[0].Recovered)
+	Equal(t, 10*time.Minute, downtimes[0].EscalatedAfter)
+}
+
+func Test_Store_Escalations(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Environments[0].Notifications = []Notification{
+		{Type: "slack"},
+		{Type: "pagerduty", EscalateAfter: 10 * time.Minute},
+		{Type: "email", EscalateAfter: 30 * time.Minute},
+	}
+	notifyMock := &NotifyMock{}
+	store, err := NewStore(cfg, notifyMock)
+	NoError(t, err)
+	defer store.Close()
+
+	NoError(t, store.InsertResult(downResult("check1")))
+	NoError(t, store.InsertResult(downResult("check1")))
+	NoError(t, store.InsertResult(downResult("check2")))
+	notifyMock.reset()
+
+	start := time.Now()
+	NoError(t, store.checkForEscalations(start.Add(5*time.Minute)))
+	notifyMock.AssertNoNotifications(t)
+
+	// check2 has not reached the fail threshold, yet
+	NoError(t, store.checkForEscalations(start.Add(11*time.Minute)))
+	Equal(t, []string{"pagerduty:check1"}, notifyMock.escalations)
+	notifyMock.reset()
+
+	NoError(t, store.checkForEscalations(start.Add(20*time.Minute)))
+	notifyMock.AssertNoNotifications(t)
+
+	NoError(t, store.checkForEscalations(start.Add(31*time.Minute)))
+	Equal(t, []string{"email:check1"}, notifyMock.escalations)
+	notifyMock.reset()
+
+	NoError(t, store.checkForEscalations(start.Add(60*time.Minute)))
+	notifyMock.AssertNoNotifications(t)
+
+	// the recovery is sent to the targets, the downtime was escalated to
+	NoError(t, store.InsertResult(upResult("check1")))
+	Equal(t, []string{"slack", "pagerduty", "email"}, notifyMock.upTypes)
+	notifyMock.reset()
+
+	NoError(t, store.InsertResult(downResult("check2")))
+	NoError(t, store.InsertResult(upResult("check2")))
+	Equal(t, []string{"slack"}, notifyMock.upTypes)
+
+	downtimes, err := store.Downtimes("testEnv", 0, 30)
+	NoError(t, err)
+	for _, d := range downtimes {
+		if d.Check == "check1" {
+			Equal(t, 30*time.Minute, d.EscalatedAfter)
+		}
+	}
+}
+
+func Test_Store_Escalations_Acknowledged(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Environments[0].Notifications = []Notification{
+		{Type: "slack"},
+		{Type: "pagerduty", EscalateAfter: 10 * time.Minute},
+	}
+	notifyMock := &NotifyMock{}
+	store, err := NewStore(cfg, notifyMock)
+	NoError(t, err)
+	defer store.Close()
+
+	NoError(t, store.InsertResult(downResult("check1")))
+	NoError(t, store.InsertResult(downResult("check1")))
+	notifyMock.reset()
+
+	downtimes, err := store.Downtimes("testEnv", 0, 30)
+	NoError(t, err)
+	_, _, err = store.AckDowntime("testEnv", int(downtimes[0].Id), "alice", "", time.Now())
+	NoError(t, err)
+
+	NoError(t, store.checkForEscalations(time.Now().Add(11*time.Minute)))
+	notifyMock.AssertNoNotifications(t)
+}
+
+func Test_Store_DegradedLifecycle(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Environments[0].Notifications = []Notification{
+		{Type: "slack", Statuses: []string{"DOWN", "degraded"}},
+		{Type: "pagerduty"},
+	}
+	notifyMock := &NotifyMock{}
+	store, err := NewStore(cfg, notifyMock)
+	NoError(t, err)
+	defer store.Close()
+
+	// UP -> DEGRADED
+	NoError(t, store.InsertResult(degradedResult("check1")))
+	NoError(t, store.InsertResult(degradedResult("check1")))
+	Equal(t, []string{"slack"}, notifyMock.degradedTypes)
+	Nil(t, notifyMock.downs)
+	notifyMock.reset()
+
+	NoError(t, store.InsertResult(degradedResult("check1")))
+	notifyMock.AssertNoNotifications(t)
+
+	// DEGRADED -> DOWN
+	NoError(t, store.InsertResult(downResult("check1")))
+	Equal(t, []string{"slack", "pagerduty"}, notifyMock.downTypes)
+	Nil(t, notifyMock.degraded)
+	notifyMock.reset()
+
+	// DOWN -> DEGRADED is notified to the targets of degraded
+	NoError(t, store.InsertResult(degradedResult("check1")))
+	Equal(t, []string{"slack"}, notifyMock.degradedTypes)
+	Nil(t, notifyMock.downs)
+	notifyMock.reset()
+
+	NoError(t, store.InsertResult(degradedResult("check1")))
+	notifyMock.AssertNoNotifications(t)
+
+	// DEGRADED -> DOWN within the same downtime is not notified again
+	NoError(t, store.InsertResult(downResult("check1")))
+	notifyMock.AssertNoNotifications(t)
+
+	// DOWN -> UP
+	NoError(t, store.InsertResult(upResult("check1")))
+	Equal(t, []string{"slack", "pagerduty"}, notifyMock.upTypes)
+	notifyMock.reset()
+
+	// DEGRADED -> UP is only notified to the targets of degraded
+	NoError(t, store.InsertResult(degradedResult("check2")))
+	NoError(t, store.InsertResult(degradedResult("check2")))
+	NoError(t, store.InsertResult(upResult("check2")))
+	Equal(t, []string{"slack"}, notifyMock.degradedTypes)
+	Equal(t, []string{"slack"}, notifyMock.upTypes)
+	Nil(t, notifyMock.downs)
+}
+
+func Test_Store_DegradedStopsRemindersAndEscalations(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Environments[0].Notifications = []Notification{
+		{Type: "slack", Statuses: []string{"DOWN", "DEGRADED"}, RemindEvery: time.Hour},
+		{Type: "pagerduty", EscalateAfter: 10 * time.Minute},
+	}
+	notifyMock := &NotifyMock{}
+	store, err := NewStore(cfg, notifyMock)
+	NoError(t, err)
+	defer store.Close()
+
+	NoError(t, store.InsertResult(downResult("check1")))
+	NoError(t, store.InsertResult(downResult("check1")))
+	Equal(t, []string{"slack"}, notifyMock.downTypes)
+	notifyMock.reset()
+
+	// DOWN -> DEGRADED
+	NoError(t, store.InsertResult(degradedResult("check1")))
+	Equal(t, []string{"slack"}, notifyMock.degradedTypes)
+	notifyMock.reset()
+
+	NoError(t, store.checkForEscalations(time.Now().Add(11*time.Minute)))
+	NoError(t, store.checkForReminders(time.Now().Add(2*time.Hour)))
+	notifyMock.AssertNoNotifications(t)
+
+	// DEGRADED -> DOWN is reminded and escalated again
+	NoError(t, store.InsertResult(downResult("check1")))
+	NoError(t, store.checkForEscalations(time.Now().Add(11*time.Minute)))
+	Equal(t, 1, len(notifyMock.escalations))
+	NoError(t, store.checkForReminders(time.Now().Add(2*time.Hour)))
+	Equal(t, []string{"slack"}, notifyMock.reminderTypes)
+}
+
+func Test_Store_NotificationRouting(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Environments[0].Checks[0].Team = "payments"
+	cfg.Environments[0].Notifications = []Notification{
+		{Type: "slack", Teams: []string{"payments"}},
+		{Type: "email"},
+	}
+	notifyMock := &NotifyMock{}
+	store, err := NewStore(cfg, notifyMock)
+	NoError(t, err)
+	defer store.Close()
+
+	NoError(t, store.InsertResult(downResult("check2")))
+	NoError(t, store.InsertResult(downResult("check2")))
+	Equal(t, []string{"email"}, notifyMock.downTypes)
+	notifyMock.reset()
+
+	NoError(t, store.InsertResult(downResult("check1")))
+	NoError(t, store.InsertResult(downResult("check1")))
+	Equal(t, []string{"slack", "email"}, notifyMock.downTypes)
+	notifyMock.reset()
+
+	NoError(t, store.InsertResult(upResult("check1")))
+	NoError(t, store.InsertResult(upResult("check2")))
+	Equal(t, []string{"slack", "email", "email"}, notifyMock.upTypes)
+}
+
 func dumpDB(file string) {
 	out, err := exec.Command("sqlite3", file, ".dump").Output()
 	if err != nil {
@@ -161,6 +614,19 @@ func downResult(check string) Result {
 	}
 }
 
+func degradedResult(check string) Result {
+	result := downResult(check)
+	result.Status = StatusDegraded
+	return result
+}
+
+// localTimezone sets time.Local for a test and returns the function to restore it.
+func localTimezone(loc *time.Location) func() {
+	local := time.Local
+	time.Local = loc
+	return func() { time.Local = local }
+}
+
 func testConfig(t *testing.T) *Config {
 	f, err := ioutil.TempFile("", "insantus_unittest")
 	NoError(t, err)
@@ -171,7 +637,7 @@ func testConfig(t *testing.T) *Config {
 			Env{
 				Id:            "testEnv",
 				Name:          "testEnv",
-				Notifications: []Notification{},
+				Notifications: []Notification{{Type: "mock"}},
 				Checks: []Check{
 					Check{
 						Id:   "check1",
@@ -190,25 +656,113 @@ func testConfig(t *testing.T) *Config {
 }
 
 type NotifyMock struct {
-	environment string
-	downs       []*Downtime
-	ups         []*Downtime
+	environment   string
+	downs         []*Downtime
+	downTypes     []string
+	ups           []*Downtime
+	upTypes       []string
+	degraded      []*Downtime
+	degradedTypes []string
+	reminders     []*Downtime
+	reminderTypes []string
+	escalations   []string
+	failingType   string
+	// onNotify is called once on the next down, reminder or escalation notification,
+	// to simulate concurrent work
+	onNotify func()
 }
 
 func (nm *NotifyMock) reset() {
 	nm.environment = ""
 	nm.downs = nil
+	nm.downTypes = nil
 	nm.ups = nil
+	nm.upTypes = nil
+	nm.degraded = nil
+	nm.degradedTypes = nil
+	nm.reminders = nil
+	nm.reminderTypes = nil
+	nm.escalations = nil
 }
 
-func (nm *NotifyMock) NotifyDown(envId string, downtimes []*Downtime) error {
+func (nm *NotifyMock) NotifyDown(envId string, notifications []Notification, downtimes []*Downtime) error {
+	if err := nm.fail(notifications); err != nil {
+		return err
+	}
 	nm.environment = envId
 	nm.downs = downtimes
+	for _, n := range notifications {
+		nm.downTypes = append(nm.downTypes, n.Type)
+	}
+	nm.notified()
 	return nil
 }
-func (nm *NotifyMock) NotifyRecovered(envId string, downtimes []*Downtime) error {
+
+func (nm *NotifyMock) NotifyDegraded(envId string, notifications []Notification, downtimes []*Downtime) error {
+	if err := nm.fail(notifications); err != nil {
+		return err
+	}
+	nm.environment = envId
+	nm.degraded = append(nm.degraded, downtimes...)
+	for _, n := range notifications {
+		nm.degradedTypes = append(nm.degradedTypes, n.Type)
+	}
+	return nil
+}
+
+func (nm *NotifyMock) NotifyRecovered(envId string, notifications []Notification, downtimes []*Downtime) error {
+	if err := nm.fail(notifications); err != nil {
+		return err
+	}
+	nm.environment = envId
+	nm.ups = append(nm.ups, downtimes...)
+	for _, n := range notifications {
+		nm.upTypes = append(nm.upTypes, n.Type)
+	}
+	return nil
+}
+
+func (nm *NotifyMock) NotifyReminder(envId string, notifications []Notification, downtimes []*Downtime) error {
+	if err := nm.fail(notifications); err != nil {
+		return err
+	}
 	nm.environment = envId
-	nm.ups = downtimes
+	nm.reminders = append(nm.reminders, downtimes...)
+	for _, n := range notifications {
+		nm.reminderTypes = append(nm.reminderTypes, n.Type)
+	}
+	nm.notified()
+	return nil
+}
+
+func (nm *NotifyMock) NotifyEscalation(envId string, notifications []Notification, downtimes []*Downtime) error {
+	if err := nm.fail(notifications); err != nil {
+		return err
+	}
+	nm.environment = envId
+	for _, n := range notifications {
+		for _, d := range downtimes {
+			nm.escalations = append(nm.escalations, n.Type+":"+d.Check)
+		}
+	}
+	nm.notified()
+	return nil
+}
+
+func (nm *NotifyMock) notified() {
+	if nm.onNotify != nil {
+		onNotify := nm.onNotify
+		nm.onNotify = nil
+		onNotify()
+	}
+}
+
+func (nm *NotifyMock) fail(notifications []Notification) error {
+	for _, n := range notifications {
+		if n.Type == nm.failingType {
+			return fmt.Errorf("sending %v failed", n.Type)
+		}
+	}
 	return nil
 }
 
@@ -216,4 +770,27 @@ func (nm *NotifyMock) AssertNoNotifications(t *testing.T) {
 	Equal(t, "", nm.environment)
 	Nil(t, nm.downs)
 	Nil(t, nm.ups)
+	Nil(t, nm.degraded)
+	Nil(t, nm.reminders)
+	Nil(t, nm.escalations)
+}
+
+func Test_Store_ConvertTimesToUTC(t *testing.T) {
+	cfg := testConfig(t)
+	store, err := NewStore(cfg, &NotifyMock{})
+	NoError(t, err)
+
+	// a result stored in local time by an older version
+	NoError(t, store.db.Exec(`INSERT INTO result (environment, "check", status, timestamp) VALUES (?, ?, ?, ?)`,
+		"testEnv", "check1", StatusUp, "2026-09-01 12:00:00.123456789+09:00").Error)
+	NoError(t, store.db.Exec(`PRAGMA user_version = 0`).Error)
+	store.Close()
+
+	store, err = NewStore(cfg, &NotifyMock{})
+	NoError(t, err)
+	defer store.Close()
+
+	var timestamp string
+	NoError(t, store.db.DB().QueryRow(`SELECT CAST(timestamp AS TEXT) FROM result`).Scan(&timestamp))
+	Equal(t, "2026-09-01 03:00:00.123+00:00", timestamp)
 }
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type TcpCheck struct {
+	environmentId string
+	checkId       string
+	name          string
+	timeout       time.Duration
+	host          string
+	port          int
+	send          string
+	expect        string
+}
+
+func NewTcpCheck(environmentId, checkId, name string, timeout time.Duration, params map[string]string) (*TcpCheck, error) {
+	c := &TcpCheck{
+		environmentId: environmentId,
+		checkId:       checkId,
+		name:          name,
+		host:          params["host"],
+		send:          params["send"],
+		expect:        params["expect"],
+	}
+
+	if c.host == "" {
+		return nil, errors.New("missing host")
+	}
+
+	port, err := strconv.Atoi(params["port"])
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing port")
+	}
+	c.port = port
+
+	d, err := checkTimeout(timeout, params)
+	if err != nil {
+		return nil, err
+	}
+	c.timeout = d
+
+	return c, nil
+}
+
+func (c *TcpCheck) Check(ctx context.Context) []Result {
+	mainResult := NewResult(c.environmentId, c.checkId, c.name)
+
+	mainResult.Status, mainResult.Message, mainResult.Detail = c.execute(ctx)
+
+	mainResult.Duration = int(time.Since(mainResult.Timestamp) / time.Millisecond)
+
+	results := []Result{mainResult}
+
+	return results
+}
+
+func (c *TcpCheck) execute(ctx context.Context) (status, message, detail string) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", c.hostAndPort())
+	if err != nil {
+		return StatusDown, err.Error(), ""
+	}
+	defer conn.Close()
+
+	deadline, _ := ctx.Deadline()
+	err = conn.SetDeadline(deadline)
+	if err != nil {
+		return StatusDown, err.Error(), ""
+	}
+	// abort a pending read or write, if the context gets cancelled
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if c.send != "" {
+		_, err := conn.Write([]byte(c.send))
+		if err != nil {
+			return StatusDown, fmt.Sprintf("could not send data: %v", err), ""
+		}
+	}
+
+	if c.expect != "" {
+		received, err := c.readUntilExpected(conn)
+		if !bytes.Contains(received, []byte(c.expect)) {
+			if err != nil {
+				return StatusDown, fmt.Sprintf("missing string %q in response: %v", c.expect, err), string(received)
+			}
+			return StatusDown, fmt.Sprintf("missing string %q in response", c.expect), string(received)
+		}
+	}
+
+	return StatusUp, "", ""
+}
+
+// readUntilExpected reads from the connection, until the expected string
+// was received, the connection was closed or the deadline exceeded.
+func (c *TcpCheck) readUntilExpected(conn net.Conn) ([]byte, error) {
+	received := []byte{}
+	buff := make([]byte, 4096)
+	for !bytes.Contains(received, []byte(c.expect)) {
+		n, err := conn.Read(buff)
+		received = append(received, buff[:n]...)
+		if err != nil {
+			return received, err
+		}
+	}
+	return received, nil
+}
+
+func (c *TcpCheck) hostAndPort() string {
+	return net.JoinHostPort(c.host, strconv.Itoa(c.port))
+}
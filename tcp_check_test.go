@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_TcpCheck(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.Write([]byte("+OK ready\r\n"))
+				line, err := bufio.NewReader(conn).ReadString('\n')
+				if err == nil && line == "PING\r\n" {
+					conn.Write([]byte("+PONG\r\n"))
+				}
+			}()
+		}
+	}()
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+
+	closedListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedPort := strconv.Itoa(closedListener.Addr().(*net.TCPAddr).Port)
+	closedListener.Close()
+
+	for _, test := range []struct {
+		name   string
+		params map[string]string
+
+		expectedStatus string
+		expectedDetail string
+	}{
+		{
+			name:           "connect",
+			params:         map[string]string{"port": port},
+			expectedStatus: StatusUp,
+		},
+		{
+			name:           "connection refused",
+			params:         map[string]string{"port": closedPort},
+			expectedStatus: StatusDown,
+		},
+		{
+			name:           "expect banner",
+			params:         map[string]string{"port": port, "expect": "+OK"},
+			expectedStatus: StatusUp,
+		},
+		{
+			name:           "send and expect",
+			params:         map[string]string{"port": port, "send": "PING\r\n", "expect": "+PONG"},
+			expectedStatus: StatusUp,
+		},
+		{
+			name:           "unexpected response",
+			params:         map[string]string{"port": port, "send": "QUIT\r\n", "expect": "+PONG"},
+			expectedStatus: StatusDown,
+			expectedDetail: "+OK ready\r\n",
+		},
+		{
+			name:           "expect timeout",
+			params:         map[string]string{"port": port, "expect": "+PONG", "timeout": "100ms"},
+			expectedStatus: StatusDown,
+			expectedDetail: "+OK ready\r\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			check, err := NewTcpCheck(
+				"prod",
+				"test-check",
+				"test check",
+				0,
+				mergeParams(map[string]string{"host": "127.0.0.1"}, test.params),
+			)
+			require.NoError(t, err)
+
+			results := check.Check(context.Background())
+
+			require.Equal(t, 1, len(results))
+			assert.Equal(t, test.expectedStatus, results[0].Status)
+			assert.Equal(t, test.expectedDetail, results[0].Detail)
+		})
+	}
+}
+
+func Test_TcpCheck_InvalidParams(t *testing.T) {
+	_, err := NewTcpCheck("prod", "test-check", "test check", 0, map[string]string{"port": "80"})
+	assert.Error(t, err)
+
+	_, err = NewTcpCheck("prod", "test-check", "test check", 0, map[string]string{"host": "localhost", "port": "http"})
+	assert.Error(t, err)
+}
+
+func Test_TcpCheck_Cancel(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+
+	check, err := NewTcpCheck("prod", "test-check", "test check", time.Minute, map[string]string{
+		"host":   "127.0.0.1",
+		"port":   strconv.Itoa(listener.Addr().(*net.TCPAddr).Port),
+		"expect": "+OK",
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	results := check.Check(ctx)
+
+	require.Equal(t, 1, len(results))
+	assert.Equal(t, StatusDown, results[0].Status)
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+}
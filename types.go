@@ -1,6 +1,7 @@
 package main
 
 import (
+	"strings"
 	"time"
 )
 
@@ -8,6 +9,9 @@ var (
 	StatusUp       = "UP"
 	StatusDown     = "DOWN"
 	StatusDegraded = "DEGRADED"
+
+	// StatusMaintenance is shown on the status page for failing checks within a maintenance window
+	StatusMaintenance = "MAINTENANCE"
 )
 
 type Result struct {
@@ -20,6 +24,11 @@ type Result struct {
 	Detail      string
 	Duration    int
 	Timestamp   time.Time `sql:"index"`
+	Maintenance bool
+
+	// CertExpiry is the expiry of the certificate checked by a cert check.
+	// It is not stored, but exported as metric.
+	CertExpiry time.Time `gorm:"-" json:"-"`
 }
 
 func NewResult(environment, check, name string) Result {
@@ -53,27 +62,121 @@ type CheckStatus struct {
 	Duration     int       `json:"duration"`
 	LastResultId uint      `json:"lastResultId"`
 	Updated      time.Time `json:"updated" sql:"index"`
+	Maintenance  bool      `json:"maintenance"`
 }
 
 type Downtime struct {
-	Id                uint      `json:"id" gorm:"primary_key"`
-	Environment       string    `json:"environment" sql:"type:varchar(50);index"`
-	Check             string    `json:"check" sql:"type:varchar(50);index"`
-	Name              string    `json:"name"`
-	Message           string    `json:"message"`
-	Start             time.Time `json:"start"`
-	End               time.Time `json:"end"`
-	FailCount         int       `json:"failCount"`
-	LastResultId      uint      `json:"lastResultId"`
-	Recovered         bool      `json:"recovered" sql:"index"`
-	Comment           string    `json:"comment"`
-	DownNotifySent    bool      `json:"downNotifySent"`
-	DownNotifyTime    time.Time `json:"downNotifyTime"`
-	RecoverNotifySent bool      `json:"recoverNotifySent"`
-	RecoverNotifyTime time.Time `json:"recoverNotifyTime"`
+	Id                 uint          `json:"id" gorm:"primary_key"`
+	Environment        string        `json:"environment" sql:"type:varchar(50);index"`
+	Check              string        `json:"check" sql:"type:varchar(50);index"`
+	Name               string        `json:"name"`
+	Status             string        `json:"status"`
+	Message            string        `json:"message"`
+	Start              time.Time     `json:"start"`
+	End                time.Time     `json:"end"`
+	FailCount          int           `json:"failCount"`
+	LastResultId       uint          `json:"lastResultId"`
+	Recovered          bool          `json:"recovered" sql:"index"`
+	Comment            string        `json:"comment"`
+	CommentBy          string        `json:"commentBy"`
+	CommentTime        time.Time     `json:"commentTime"`
+	Acknowledged       bool          `json:"acknowledged"`
+	AckBy              string        `json:"ackBy"`
+	AckTime            time.Time     `json:"ackTime"`
+	AckNote            string        `json:"ackNote"`
+	DownNotifySent     bool          `json:"downNotifySent"`
+	DownNotifyTime     time.Time     `json:"downNotifyTime"`
+	DegradedNotifySent bool          `json:"degradedNotifySent"`
+	DegradedNotifyTime time.Time     `json:"degradedNotifyTime"`
+	LastReminderTime   time.Time     `json:"lastReminderTime"`
+	EscalatedAfter     time.Duration `json:"escalatedAfter"`
+	RecoverNotifySent  bool          `json:"recoverNotifySent"`
+	RecoverNotifyTime  time.Time     `json:"recoverNotifyTime"`
+	Maintenance        bool          `json:"maintenance"`
+}
+
+// StatusChange records the failing status of a downtime from Time on,
+// so that a downtime changing between DOWN and DEGRADED is only counted as down for its DOWN periods.
+type StatusChange struct {
+	Id         uint      `json:"id" gorm:"primary_key"`
+	DowntimeId uint      `json:"downtimeId" sql:"index"`
+	Status     string    `json:"status"`
+	Time       time.Time `json:"time"`
+}
+
+// MaintenanceWindow is a period, in which failures of the checks are expected.
+// The windows created by the api are stored, the ones of the configuration
+// are calculated from their Maintenance schedule.
+type MaintenanceWindow struct {
+	Id          uint      `json:"id" gorm:"primary_key"`
+	Environment string    `json:"environment" sql:"type:varchar(50);index"`
+	Name        string    `json:"name"`
+	Checks      []string  `json:"checks" gorm:"-"`
+	CheckIds    string    `json:"-"`
+	Start       time.Time `json:"start" sql:"index"`
+	End         time.Time `json:"end" sql:"index"`
+	Comment     string    `json:"comment"`
+	Configured  bool      `json:"configured" gorm:"-"`
+}
+
+func (w *MaintenanceWindow) BeforeSave() error {
+	w.CheckIds = strings.Join(w.Checks, ",")
+	return nil
+}
+
+func (w *MaintenanceWindow) AfterFind() error {
+	w.Checks = []string{}
+	if w.CheckIds != "" {
+		w.Checks = strings.Split(w.CheckIds, ",")
+	}
+	return nil
+}
+
+// activeAt returns true, if t is within the window.
+func (w *MaintenanceWindow) activeAt(t time.Time) bool {
+	return !t.Before(w.Start) && t.Before(w.End)
+}
+
+// covers returns true, if the window applies to the check.
+// A window without checks applies to the whole environment.
+func (w *MaintenanceWindow) covers(check string) bool {
+	return len(w.Checks) == 0 || contains(w.Checks, check)
+}
+
+var (
+	NotificationDown       = "down"
+	NotificationDegraded   = "degraded"
+	NotificationRecovered  = "recovered"
+	NotificationReminder   = "reminder"
+	NotificationEscalation = "escalation"
+
+	DeliveryPending = "pending"
+	DeliverySending = "sending"
+	DeliverySent    = "sent"
+	DeliveryDead    = "dead"
+)
+
+// NotificationDelivery is an entry of the notification outbox:
+// the delivery of a notification about some downtimes to one target.
+type NotificationDelivery struct {
+	Id          uint      `json:"id" gorm:"primary_key"`
+	Environment string    `json:"environment" sql:"type:varchar(50);index"`
+	Kind        string    `json:"kind"`
+	Type        string    `json:"type"`
+	Target      string    `json:"-"`
+	DowntimeIds string    `json:"downtimeIds"`
+	Status      string    `json:"status" sql:"type:varchar(50);index"`
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"lastError"`
+	Created     time.Time `json:"created"`
+	NextAttempt time.Time `json:"nextAttempt" sql:"index"`
+	Sent        time.Time `json:"sent"`
 }
 
 type Notifyer interface {
-	NotifyDown(envId string, downtimes []*Downtime) error
-	NotifyRecovered(envId string, downtimes []*Downtime) error
+	NotifyDown(envId string, notifications []Notification, downtimes []*Downtime) error
+	NotifyDegraded(envId string, notifications []Notification, downtimes []*Downtime) error
+	NotifyRecovered(envId string, notifications []Notification, downtimes []*Downtime) error
+	NotifyReminder(envId string, notifications []Notification, downtimes []*Downtime) error
+	NotifyEscalation(envId string, notifications []Notification, downtimes []*Downtime) error
 }
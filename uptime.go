@@ -0,0 +1,257 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Uptime is the availability of a check or of a whole environment within a time range.
+// Only downtimes, which reached the fail threshold and were DOWN for some time, count as incidents,
+// degraded checks are still available.
+type Uptime struct {
+	Environment        string    `json:"environment"`
+	Check              string    `json:"check,omitempty"`
+	Name               string    `json:"name"`
+	From               time.Time `json:"from"`
+	To                 time.Time `json:"to"`
+	UptimePercent      float64   `json:"uptimePercent"`
+	Incidents          int       `json:"incidents"`
+	ObservedSeconds    float64   `json:"observedSeconds"`
+	DowntimeSeconds    float64   `json:"downtimeSeconds"`
+	MaintenanceSeconds float64   `json:"maintenanceSeconds"`
+	MTTRSeconds        float64   `json:"mttrSeconds"`
+	MTBFSeconds        float64   `json:"mtbfSeconds"`
+}
+
+type UptimeReport struct {
+	Environment Uptime    `json:"environment"`
+	Checks      []*Uptime `json:"checks"`
+}
+
+// period is a time range, from start to the exclusive end.
+type period struct {
+	start time.Time
+	end   time.Time
+}
+
+// Uptime calculates the availability of the checks and of the environment from the downtimes.
+// Only the DOWN periods of a downtime count, as recorded by its status changes.
+// The environment is down, while any of its checks is down.
+// With excludeMaintenance, the maintenance windows are neither counted as up nor as down.
+func (store *Store) Uptime(environment string, from, to time.Time, excludeMaintenance bool) (*UptimeReport, error) {
+	env, _ := store.cfg.EnvById(environment)
+	// the times are stored in UTC
+	from, to = from.UTC(), to.UTC()
+
+	downtimes := []*Downtime{}
+	err := store.db.
+		Where(`environment = ? AND start < ? AND (recovered = 0 OR "end" > ?)`, environment, to, from).
+		Order("start").
+		Find(&downtimes).
+		Error
+	if err != nil {
+		return nil, errors.Wrap(err, "query downtimes")
+	}
+
+	changes, err := store.statusChanges(downtimes)
+	if err != nil {
+		return nil, errors.Wrap(err, "query status changes")
+	}
+
+	windows := []*MaintenanceWindow{}
+	if excludeMaintenance {
+		windows, err = store.maintenanceWindowsBetween(environment, from, to)
+		if err != nil {
+			return nil, errors.Wrap(err, "query maintenance windows")
+		}
+	}
+
+	report := &UptimeReport{Checks: []*Uptime{}}
+	allDown := []period{}
+	envExcluded := []period{}
+	for _, w := range windows {
+		if len(w.Checks) == 0 {
+			envExcluded = append(envExcluded, period{w.Start, w.End})
+		}
+	}
+	envExcluded = clipPeriods(mergePeriods(envExcluded), from, to)
+
+	for _, c := range env.Checks {
+		excluded := []period{}
+		for _, w := range windows {
+			if w.covers(c.Id) {
+				excluded = append(excluded, period{w.Start, w.End})
+			}
+		}
+		excluded = clipPeriods(mergePeriods(excluded), from, to)
+
+		incidents := 0
+		down := []period{}
+		for _, d := range downtimes {
+			if d.Check != c.Id {
+				continue
+			}
+			end := to
+			if d.Recovered {
+				end = d.End
+			}
+			if !env.failThresholdReached(d, end) {
+				continue
+			}
+			p := subtractPeriods(clipPeriods(downPeriods(d, changes[d.Id], end), from, to), excluded)
+			if totalDuration(p) > 0 {
+				incidents++
+				down = append(down, p...)
+			}
+		}
+		down = mergePeriods(down)
+		allDown = append(allDown, down...)
+
+		u := newUptime(from, to, totalDuration(down), totalDuration(excluded), incidents)
+		u.Environment = environment
+		u.Check = c.Id
+		u.Name = c.Name
+		report.Checks = append(report.Checks, u)
+	}
+
+	allDown = mergePeriods(allDown)
+	report.Environment = *newUptime(from, to, totalDuration(allDown), totalDuration(envExcluded), len(allDown))
+	report.Environment.Environment = environment
+	report.Environment.Name = env.Name
+	return report, nil
+}
+
+// statusChanges loads the status changes of the downtimes, ordered by time.
+func (store *Store) statusChanges(downtimes []*Downtime) (map[uint][]StatusChange, error) {
+	ids := []uint{}
+	for _, d := range downtimes {
+		ids = append(ids, d.Id)
+	}
+	changes := map[uint][]StatusChange{}
+	if len(ids) == 0 {
+		return changes, nil
+	}
+
+	rows := []StatusChange{}
+	err := store.db.Where(`downtime_id IN (?)`, ids).Order("time, id").Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range rows {
+		changes[c.DowntimeId] = append(changes[c.DowntimeId], c)
+	}
+	return changes, nil
+}
+
+// downPeriods returns the periods until end, in which the downtime had the status DOWN.
+// Downtimes without status changes are taken as a whole by their last status.
+func downPeriods(d *Downtime, changes []StatusChange, end time.Time) []period {
+	if len(changes) == 0 {
+		if d.Status != StatusDown {
+			return []period{}
+		}
+		return []period{{d.Start, end}}
+	}
+
+	periods := []period{}
+	for i, c := range changes {
+		if c.Status != StatusDown {
+			continue
+		}
+		p := period{c.Time, end}
+		if i+1 < len(changes) && changes[i+1].Time.Before(end) {
+			p.end = changes[i+1].Time
+		}
+		if p.end.After(p.start) {
+			periods = append(periods, p)
+		}
+	}
+	return periods
+}
+
+func newUptime(from, to time.Time, downtime, maintenance time.Duration, incidents int) *Uptime {
+	observed := to.Sub(from) - maintenance
+	u := &Uptime{
+		From:               from,
+		To:                 to,
+		UptimePercent:      100,
+		Incidents:          incidents,
+		ObservedSeconds:    observed.Seconds(),
+		DowntimeSeconds:    downtime.Seconds(),
+		MaintenanceSeconds: maintenance.Seconds(),
+	}
+	if observed > 0 {
+		u.UptimePercent = 100 * float64(observed-downtime) / float64(observed)
+	}
+	if incidents > 0 {
+		u.MTTRSeconds = downtime.Seconds() / float64(incidents)
+		u.MTBFSeconds = (observed - downtime).Seconds() / float64(incidents)
+	}
+	return u
+}
+
+// mergePeriods sorts the periods and joins the overlapping ones.
+func mergePeriods(periods []period) []period {
+	sort.Slice(periods, func(i, j int) bool {
+		return periods[i].start.Before(periods[j].start)
+	})
+	merged := []period{}
+	for _, p := range periods {
+		last := len(merged) - 1
+		if last >= 0 && !p.start.After(merged[last].end) {
+			if p.end.After(merged[last].end) {
+				merged[last].end = p.end
+			}
+			continue
+		}
+		merged = append(merged, p)
+	}
+	return merged
+}
+
+// clipPeriods limits the periods to the range from to.
+func clipPeriods(periods []period, from, to time.Time) []period {
+	clipped := []period{}
+	for _, p := range periods {
+		if p.start.Before(from) {
+			p.start = from
+		}
+		if p.end.After(to) {
+			p.end = to
+		}
+		if p.end.After(p.start) {
+			clipped = append(clipped, p)
+		}
+	}
+	return clipped
+}
+
+// subtractPeriods removes the merged periods cut from the periods.
+func subtractPeriods(periods, cut []period) []period {
+	result := []period{}
+	for _, p := range periods {
+		for _, c := range cut {
+			if !c.end.After(p.start) || !c.start.Before(p.end) {
+				continue
+			}
+			if c.start.After(p.start) {
+				result = append(result, period{p.start, c.start})
+			}
+			p.start = c.end
+		}
+		if p.end.After(p.start) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+func totalDuration(periods []period) time.Duration {
+	var d time.Duration
+	for _, p := range periods {
+		d += p.end.Sub(p.start)
+	}
+	return d
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Store_Uptime(t *testing.T) {
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(10 * time.Hour)
+	at := func(d time.Duration) time.Time { return from.Add(d) }
+
+	cfg := testConfig(t)
+	cfg.Environments[0].Maintenance = []Maintenance{
+		{Id: "deploy", Checks: []string{"check2"}, Start: at(9 * time.Hour), Duration: 2 * time.Hour},
+	}
+	store, err := NewStore(cfg, &NotifyMock{})
+	require.NoError(t, err)
+	defer store.Close()
+
+	for _, d := range []*Downtime{
+		// before the range
+		{Check: "check1", Status: StatusDown, FailCount: 2, Start: at(-3 * time.Hour), End: at(-2 * time.Hour), Recovered: true},
+		{Check: "check1", Status: StatusDown, FailCount: 3, Start: at(time.Hour), End: at(2 * time.Hour), Recovered: true},
+		// below the fail threshold
+		{Check: "check1", Status: StatusDown, FailCount: 1, Start: at(3 * time.Hour), End: at(3*time.Hour + 10*time.Minute), Recovered: true},
+		// degraded is still available
+		{Check: "check1", Status: StatusDegraded, FailCount: 5, Start: at(4 * time.Hour), End: at(5 * time.Hour), Recovered: true},
+		{Check: "check2", Status: StatusDown, FailCount: 2, Start: at(90 * time.Minute), End: at(150 * time.Minute), Recovered: true},
+		{Check: "check2", Status: StatusDown, FailCount: 2, Start: at(9 * time.Hour)},
+	} {
+		d.Environment = "testEnv"
+		require.NoError(t, store.db.Create(d).Error)
+	}
+	require.NoError(t, store.CreateMaintenanceWindow(&MaintenanceWindow{
+		Environment: "testEnv", Name: "db", Start: at(time.Hour), End: at(90 * time.Minute),
+	}))
+
+	report, err := store.Uptime("testEnv", from, to, false)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(report.Checks))
+
+	check1 := report.Checks[0]
+	assert.Equal(t, "check1", check1.Check)
+	assert.Equal(t, 1, check1.Incidents)
+	assert.InDelta(t, 90, check1.UptimePercent, 0.001)
+	assert.Equal(t, 3600.0, check1.DowntimeSeconds)
+	assert.Equal(t, 3600.0, check1.MTTRSeconds)
+	assert.Equal(t, 9*3600.0, check1.MTBFSeconds)
+
+	check2 := report.Checks[1]
+	assert.Equal(t, 2, check2.Incidents)
+	assert.InDelta(t, 80, check2.UptimePercent, 0.001)
+
+	// the environment is down, while any check is down
+	assert.Equal(t, "testEnv", report.Environment.Environment)
+	assert.Equal(t, 2, report.Environment.Incidents)
+	assert.Equal(t, 2.5*3600, report.Environment.DowntimeSeconds)
+	assert.InDelta(t, 75, report.Environment.UptimePercent, 0.001)
+
+	// without the maintenance windows
+	report, err = store.Uptime("testEnv", from, to, true)
+	require.NoError(t, err)
+
+	check1 = report.Checks[0]
+	assert.Equal(t, 1, check1.Incidents)
+	assert.Equal(t, 1800.0, check1.DowntimeSeconds)
+	assert.Equal(t, 1800.0, check1.MaintenanceSeconds)
+	assert.InDelta(t, 100*9/9.5, check1.UptimePercent, 0.001)
+
+	check2 = report.Checks[1]
+	assert.Equal(t, 1, check2.Incidents)
+	assert.Equal(t, 3600.0, check2.DowntimeSeconds)
+	assert.Equal(t, 1.5*3600, check2.MaintenanceSeconds)
+	assert.Equal(t, 8.5*3600, check2.ObservedSeconds)
+
+	assert.Equal(t, 1, report.Environment.Incidents)
+	assert.Equal(t, 3600.0, report.Environment.DowntimeSeconds)
+	assert.Equal(t, 1800.0, report.Environment.MaintenanceSeconds)
+}
+
+func Test_Store_Uptime_StatusChanges(t *testing.T) {
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(10 * time.Hour)
+	at := func(d time.Duration) time.Time { return from.Add(d) }
+
+	store, err := NewStore(testConfig(t), &NotifyMock{})
+	require.NoError(t, err)
+	defer store.Close()
+
+	for _, d := range []struct {
+		downtime *Downtime
+		changes  []StatusChange
+	}{
+		// DOWN -> DEGRADED -> UP counts only the DOWN part
+		{&Downtime{Check: "check1", Status: StatusDegraded, FailCount: 5, Start: at(time.Hour), End: at(4 * time.Hour), Recovered: true},
+			[]StatusChange{{Status: StatusDown, Time: at(time.Hour)}, {Status: StatusDegraded, Time: at(2 * time.Hour)}}},
+		// an open downtime, which is degraded again
+		{&Downtime{Check: "check1", Status: StatusDegraded, FailCount: 5, Start: at(9 * time.Hour)},
+			[]StatusChange{{Status: StatusDown, Time: at(9 * time.Hour)}, {Status: StatusDegraded, Time: at(9*time.Hour + 30*time.Minute)}}},
+		// a long DEGRADED, which ends DOWN, counts only the DOWN part
+		{&Downtime{Check: "check2", Status: StatusDown, FailCount: 5, Start: at(4 * time.Hour), End: at(8 * time.Hour), Recovered: true},
+			[]StatusChange{{Status: StatusDegraded, Time: at(4 * time.Hour)}, {Status: StatusDown, Time: at(7 * time.Hour)}}},
+	} {
+		d.downtime.Environment = "testEnv"
+		require.NoError(t, store.db.Create(d.downtime).Error)
+		for _, c := range d.changes {
+			c.DowntimeId = d.downtime.Id
+			require.NoError(t, store.db.Create(&c).Error)
+		}
+	}
+
+	report, err := store.Uptime("testEnv", from, to, false)
+	require.NoError(t, err)
+
+	check1 := report.Checks[0]
+	assert.Equal(t, 2, check1.Incidents)
+	assert.Equal(t, 1.5*3600, check1.DowntimeSeconds)
+
+	check2 := report.Checks[1]
+	assert.Equal(t, 1, check2.Incidents)
+	assert.Equal(t, 3600.0, check2.DowntimeSeconds)
+
+	assert.Equal(t, 3, report.Environment.Incidents)
+	assert.Equal(t, 2.5*3600, report.Environment.DowntimeSeconds)
+}
+
+func Test_Store_Uptime_LocalTimezone(t *testing.T) {
+	defer localTimezone(time.FixedZone("JST", 9*60*60))()
+
+	store, err := NewStore(testConfig(t), &NotifyMock{})
+	require.NoError(t, err)
+	defer store.Close()
+
+	for _, result := range []Result{downResult("check1"), downResult("check1"), upResult("check1")} {
+		require.NoError(t, store.InsertResult(result))
+	}
+	now := time.Now()
+	require.NoError(t, store.CreateMaintenanceWindow(&MaintenanceWindow{
+		Environment: "testEnv", Name: "deploy", Checks: []string{"check2"}, Start: now.UTC().Add(-time.Hour), End: now.UTC().Add(time.Hour),
+	}))
+
+	// a range in local time
+	report, err := store.Uptime("testEnv", now.Add(-time.Hour), now.Add(time.Minute), true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Checks[0].Incidents)
+	assert.True(t, report.Checks[0].DowntimeSeconds > 0)
+	assert.Equal(t, 61*60.0, report.Checks[1].MaintenanceSeconds)
+}
+
+func Test_Store_StatusChanges(t *testing.T) {
+	store, err := NewStore(testConfig(t), &NotifyMock{})
+	require.NoError(t, err)
+	defer store.Close()
+
+	for _, result := range []Result{
+		degradedResult("check1"), degradedResult("check1"), downResult("check1"), downResult("check1"), degradedResult("check1"), upResult("check1"),
+	} {
+		require.NoError(t, store.InsertResult(result))
+	}
+
+	downtimes, err := store.Downtimes("testEnv", 0, 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(downtimes))
+
+	changes, err := store.statusChanges(downtimes)
+	require.NoError(t, err)
+	statuses := []string{}
+	for _, c := range changes[downtimes[0].Id] {
+		statuses = append(statuses, c.Status)
+	}
+	assert.Equal(t, []string{StatusDegraded, StatusDown, StatusDegraded}, statuses)
+	assert.True(t, downtimes[0].Start.Equal(changes[downtimes[0].Id][0].Time))
+}
+
+func Test_Periods(t *testing.T) {
+	t0 := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	p := func(start, end int) period {
+		return period{t0.Add(time.Duration(start) * time.Hour), t0.Add(time.Duration(end) * time.Hour)}
+	}
+
+	assert.Equal(t, []period{p(0, 3), p(4, 5)}, mergePeriods([]period{p(4, 5), p(0, 2), p(1, 3)}))
+	assert.Equal(t, []period{p(1, 2)}, clipPeriods([]period{p(0, 2), p(3, 4)}, t0.Add(time.Hour), t0.Add(3*time.Hour)))
+	assert.Equal(t, []period{p(0, 1), p(2, 3), p(4, 5)}, subtractPeriods([]period{p(0, 5)}, []period{p(1, 2), p(3, 4)}))
+	assert.Equal(t, []period{}, subtractPeriods([]period{p(1, 2)}, []period{p(0, 3)}))
+	assert.Equal(t, 3*time.Hour, totalDuration([]period{p(0, 1), p(2, 4)}))
+}